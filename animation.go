@@ -0,0 +1,282 @@
+//  animation.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// Frame is a single image of an Animation. Frames after the first may only
+// cover the part of the picture that changed, as given by their bounds.
+type Frame struct {
+	Image image.Image
+	Delay time.Duration // how long the frame is shown
+}
+
+// Animation is a sequence of frames drawn on top of each other
+type Animation struct {
+	Width  int
+	Height int
+	Frames []Frame
+}
+
+// add appends a frame showing im, or only the part of im that differs from
+// prev, or lengthens the last frame if nothing changed
+func (a *Animation) add(im, prev *image.RGBA, delay time.Duration) {
+	r := im.Bounds()
+	if prev != nil {
+		r = changedRect(im, prev)
+		if r.Empty() {
+			a.Frames[len(a.Frames)-1].Delay += delay
+			return
+		}
+	}
+
+	a.Frames = append(a.Frames, Frame{Image: compactImage(im, r), Delay: delay})
+}
+
+// changedRect returns the smallest rectangle holding every pixel that
+// differs between a and b, which have the same bounds
+func changedRect(a, b *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	bounds := a.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ra := a.Pix[a.PixOffset(bounds.Min.X, y):a.PixOffset(bounds.Max.X, y)]
+		rb := b.Pix[b.PixOffset(bounds.Min.X, y):b.PixOffset(bounds.Max.X, y)]
+		if bytes.Equal(ra, rb) {
+			continue
+		}
+
+		x0, x1 := 0, len(ra)/4
+		for x0 < x1 && bytes.Equal(ra[x0*4:x0*4+4], rb[x0*4:x0*4+4]) {
+			x0++
+		}
+		for x1 > x0 && bytes.Equal(ra[x1*4-4:x1*4], rb[x1*4-4:x1*4]) {
+			x1--
+		}
+
+		r = r.Union(image.Rect(bounds.Min.X+x0, y, bounds.Min.X+x1, y+1))
+	}
+
+	return r
+}
+
+// compactImage copies the r part of im, as a paletted image if it has no
+// more than 256 colors
+func compactImage(im *image.RGBA, r image.Rectangle) image.Image {
+	if p := exactPaletted(im, r); p != nil {
+		return p
+	}
+
+	c := image.NewRGBA(r)
+	draw.Draw(c, r, im, r.Min, draw.Src)
+	return c
+}
+
+// exactPaletted converts the r part of im to a paletted image, or returns
+// nil if it has more than 256 colors
+func exactPaletted(im image.Image, r image.Rectangle) *image.Paletted {
+	index := make(map[color.RGBA]uint8)
+	var colors color.Palette
+
+	p := image.NewPaletted(r, nil)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.RGBAModel.Convert(im.At(x, y)).(color.RGBA)
+
+			i, ok := index[c]
+			if !ok {
+				if len(colors) == 256 {
+					return nil
+				}
+				i = uint8(len(colors))
+				index[c] = i
+				colors = append(colors, c)
+			}
+
+			p.Pix[p.PixOffset(x, y)] = i
+		}
+	}
+
+	p.Palette = colors
+	return p
+}
+
+// quantize converts im to a paletted image, reduced to a fixed palette if it
+// has more than 256 colors
+func quantize(im image.Image) *image.Paletted {
+	if p := exactPaletted(im, im.Bounds()); p != nil {
+		return p
+	}
+
+	p := image.NewPaletted(im.Bounds(), palette.Plan9)
+	draw.Draw(p, p.Rect, im, p.Rect.Min, draw.Src)
+	return p
+}
+
+// EncodeGIF writes the animation as a looping GIF. Frames with more than 256
+// colors are reduced to a fixed palette.
+func (a *Animation) EncodeGIF(w io.Writer) error {
+	g := &gif.GIF{
+		Config: image.Config{Width: a.Width, Height: a.Height},
+	}
+
+	for _, f := range a.Frames {
+		p, ok := f.Image.(*image.Paletted)
+		if !ok {
+			p = quantize(f.Image)
+		}
+
+		// GIF delays are in hundredths of a second
+		g.Image = append(g.Image, p)
+		g.Delay = append(g.Delay, int(f.Delay/(10*time.Millisecond)))
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+
+	return gif.EncodeAll(w, g)
+}
+
+// EncodeAPNG writes the animation as a looping animated PNG
+func (a *Animation) EncodeAPNG(w io.Writer) error {
+	if len(a.Frames) == 0 {
+		return &OpError{Op: "APNG", Err: ErrBadOption}
+	}
+
+	aw := &apngWriter{w: w}
+
+	aw.write([]byte("\x89PNG\r\n\x1a\n"))
+
+	// 8-bit RGBA
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(a.Width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(a.Height))
+	ihdr[8] = 8
+	ihdr[9] = 6
+	aw.chunk("IHDR", ihdr)
+
+	// frame count, loop forever
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(a.Frames)))
+	aw.chunk("acTL", actl)
+
+	for i, f := range a.Frames {
+		r := f.Image.Bounds()
+
+		// delays are a fraction, in milliseconds here
+		delay := f.Delay / time.Millisecond
+		if delay > 0xFFFF {
+			delay = 0xFFFF
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], aw.next())
+		binary.BigEndian.PutUint32(fctl[4:], uint32(r.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(r.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(r.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(r.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		// dispose op none, blend op source
+		aw.chunk("fcTL", fctl)
+
+		data, err := apngImageData(f.Image)
+		if err != nil {
+			return err
+		}
+
+		// the first frame is the default image
+		if i == 0 {
+			aw.chunk("IDAT", data)
+		} else {
+			seq := make([]byte, 4)
+			binary.BigEndian.PutUint32(seq, aw.next())
+			aw.chunk("fdAT", append(seq, data...))
+		}
+	}
+
+	aw.chunk("IEND", nil)
+
+	return aw.err
+}
+
+// apngWriter writes PNG chunks, keeping the first error
+type apngWriter struct {
+	w   io.Writer
+	seq uint32
+	err error
+}
+
+func (aw *apngWriter) write(b []byte) {
+	if aw.err == nil {
+		_, aw.err = aw.w.Write(b)
+	}
+}
+
+func (aw *apngWriter) chunk(name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	aw.write(header)
+	aw.write(data)
+	aw.write(footer)
+}
+
+// next returns the next animation chunk sequence number
+func (aw *apngWriter) next() uint32 {
+	aw.seq++
+	return aw.seq - 1
+}
+
+// apngImageData compresses the rows of im as 8-bit RGBA without filtering
+func apngImageData(im image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	r := im.Bounds()
+	row := make([]byte, 1+4*r.Dx())
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBAModel.Convert(im.At(x, y)).(color.NRGBA)
+			i := 1 + 4*(x-r.Min.X)
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -16,542 +16,722 @@
 package goansi
 
 import (
-	"image"
+	"context"
 	"image/color"
-	"image/draw"
-	"strconv"
-	"strings"
 )
 
-// Character structure
-type ansiChar struct {
-	positionX       int
-	positionY       int
-	colorBackground int
-	colorForeground int
-	colorFg24       color.RGBA
-	colorBg24       color.RGBA
-	currentChar     byte
-	bold            bool
-	italics         bool
-	underline       bool
+// Ansi takes an inputFileBuffer with .ans data and returns a canvas
+func ansi(ctx context.Context, inputFileBuffer []byte, inputFileSize int64, columns int, fontName string, bits int, mode string, icecolors bool, isDizFile bool, colors Palette) (*Canvas, error) {
+	var f Font
+
+	// font selection
+	alSelectFont(&f, fontName)
+
+	// ANSi processing
+	in := newInterpreter(mode, icecolors, f.Amiga)
+	if columns != in.page.width {
+		in.page = newPage(columns, in.page.blank)
+	}
+
+	// no more rows than the file has bytes, a few cursor moves can't ask
+	// for a huge canvas
+	in.page.maxRows = min(in.page.maxRows, int(inputFileSize)+1)
+
+	var lex lexer
+
+	for loop := 0; loop < int(inputFileSize) && !in.stopped; loop++ {
+		// check now and then, a single byte does little work
+		if loop%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		lex.feed(inputFileBuffer[loop], in.handle)
+	}
+
+	// size the canvas
+	positionXMax := in.xMax + 1
+	positionYMax := in.yMax + 1
+
+	if in.ced {
+		columns = 78
+	}
+
+	if isDizFile {
+		columns = min(positionXMax, columns)
+	}
+
+	var palette Palette
+
+	if in.workbench {
+		palette = ansiPalette(colors, workbenchPalette)
+	} else {
+		palette = ansiPalette(colors, nil)
+	}
+
+	canvas := NewCanvas(columns, positionYMax, &f, palette)
+	canvas.Bits = bits
+	canvas.Transparent = mode == "transparent"
+
+	if in.ced {
+		canvas.Fill(in.page.blank)
+	}
+
+	for y := 0; y < canvas.Height && y < len(in.page.rows); y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		copy(canvas.Cells[y*canvas.Width:(y+1)*canvas.Width], in.page.rows[y])
+	}
+
+	return canvas, nil
 }
 
-// Ansi takes an inputFileBuffer with .ans data and returns an image buffer
-func ansi(inputFileBuffer []byte, inputFileSize int64, fontName string, bits int, mode string, icecolors bool, fext string) image.Image {
-	var f font
+// interpreter carries out the events of ANSi data on a page. It keeps the
+// cursor, colors and attributes that escape sequences change.
+type interpreter struct {
+	page *page
 
-	columns := 80
+	// rendering modes
+	terminal  bool // a live screen rather than a file, see newTerminalInterpreter
+	ced       bool // everything black on gray
+	workbench bool // Amiga Workbench colors, bold and blink don't brighten
+	icecolors bool // blink selects bright backgrounds
+	amiga     bool // Amiga fonts, form feed and CR aren't drawn
 
-	isDizFile := false
-	ced := false
-	transparent := false
-	workbench := false
+	// positions
+	x, y           int // cursor
+	xMax, yMax     int // furthest position written to
+	savedX, savedY int
 
-	// font selection
-	alSelectFont(&f, fontName)
+	// colors are ANSi color numbers, plus 8 for bright ones, or 256 color
+	// palette indexes from 16 on
+	fg, bg int
+
+	// 24-bit colors set by SGR 38;2 and 48;2, they stay until replaced
+	fgTrue, bgTrue color.RGBA
+
+	// 24-bit colors set by PabloDraw sequences, for the next character only
+	fg24, bg24 color.RGBA
+
+	// text attributes
+	attr Attr
+
+	// last character written, for REP
+	lastChar    byte
+	hasLastChar bool
+
+	// a SUB ended the data
+	stopped bool
+}
+
+func newInterpreter(mode string, icecolors bool, amiga bool) *interpreter {
+	in := &interpreter{
+		icecolors: icecolors,
+		amiga:     amiga,
+		fg:        7,
+	}
 
-	// to deal with the bits flag, we declared handy bool types
+	// to deal with the mode flag, we declared handy bool types
 	if mode == "ced" {
-		ced = true
-	} else if mode == "transparent" {
-		transparent = true
+		in.ced = true
 	} else if mode == "workbench" {
-		workbench = true
+		in.workbench = true
 	}
 
-	// check if current file has a .diz extension
-	if fext == ".diz" {
-		isDizFile = true
+	blank := blankCell
+	if in.ced {
+		blank = Cell{Char: ' ', Fg: 0, Bg: 7}
 	}
+	in.page = newPage(80, blank)
 
-	// image buffer
-	var imANSi draw.Image
+	return in
+}
 
-	// ANSi processing loops
-	var loop int
+// newTerminalInterpreter returns an interpreter for a terminal screen of a
+// fixed size. It behaves like a terminal rather than like ansilove: CR
+// returns the cursor, the cursor stays on the screen, line feeds at the
+// bottom scroll the screen up and a full line only wraps when the next
+// character arrives.
+func newTerminalInterpreter(width, height int, mode string, icecolors bool, amiga bool) *interpreter {
+	in := newInterpreter(mode, icecolors, amiga)
+	in.terminal = true
+	in.page = newScreen(width, height, in.page.blank)
+
+	return in
+}
 
-	// character definitions
-	var currentChar, nextChar, character byte
-	var ansiSequenceChar byte
+// handle carries out a single lexer event
+func (in *interpreter) handle(e *Event) {
+	if in.stopped {
+		return
+	}
 
-	// default color values
-	colorBackground := 0
-	colorForeground := 7
+	// TODO Make these properly scoped
+	const wrapColumn80 bool = true
+	if in.x == in.page.width && wrapColumn80 && !in.terminal {
+		in.lineFeed()
+		in.x = 0
+	}
 
-	var fgcolor color.RGBA
-	var bgcolor color.RGBA
+	switch e.Kind {
+	case EventPrint:
+		in.putChar(e.Char)
+	case EventControl:
+		in.control(e.Char)
+	case EventESC:
+		if len(e.Intermediates) == 0 {
+			in.esc(e.Char)
+		}
+	case EventCSI:
+		// private and intermediate sequences set modes we don't have
+		if e.Private == 0 && len(e.Intermediates) == 0 {
+			x, y := in.x, in.y
+			in.csi(e)
+
+			// a terminal keeps the cursor on the screen
+			if in.terminal && (in.x != x || in.y != y) {
+				in.x = max(min(in.x, in.page.width-1), 0)
+				in.y = max(min(in.y, in.page.height-1), 0)
+			}
+		}
+	}
+}
 
-	// text attributes
-	var bold, underline, italics, blink bool = false, false, false, false
+// lineFeed moves the cursor down a row, scrolling a terminal screen up when
+// it is on the bottom row
+func (in *interpreter) lineFeed() {
+	if in.terminal && in.y >= in.page.height-1 {
+		in.page.deleteRows(0, 1, in.eraseCell())
+		in.y = in.page.height - 1
+		return
+	}
 
-	// positions
-	var positionX, positionY, positionXMax, positionYMax int = 0, 0, 0, 0
-	var savedPositionY, savedPositionX int = 0, 0
-
-	// sequence parsing variables
-	var seqArrayCount int
-	var seqArray []string
-	var seqGrab string
-
-	// ANSi buffer structure array definition
-	var structIndex int
-	var ansiBuffer []ansiChar
-
-	fg24 := color.RGBA{0, 0, 0, 0}
-	bg24 := color.RGBA{0, 0, 0, 0}
-
-	// ANSi interpreter
-	for loop < int(inputFileSize)-1 {
-		currentChar = inputFileBuffer[loop]
-		nextChar = inputFileBuffer[loop+1]
-
-		// TODO Make these properly scoped
-		const wrapColumn80 bool = true
-		if positionX == 80 && wrapColumn80 {
-			positionY++
-			positionX = 0
-		}
+	in.y++
+}
 
-		// CR + LF
-		if currentChar == 13 && nextChar == 10 {
-			positionY++
-			positionX = 0
-			loop++
+// esc carries out an escape sequence other than CSI
+func (in *interpreter) esc(c byte) {
+	switch c {
+	case '7':
+		// save cursor position
+		in.savedX, in.savedY = in.x, in.y
+	case '8':
+		// restore cursor position
+		in.x, in.y = in.savedX, in.savedY
+	case 'D':
+		// index
+		in.lineFeed()
+	case 'E':
+		// next line
+		in.lineFeed()
+		in.x = 0
+	case 'M':
+		// reverse index, scrolling down at the top
+		if in.y > 0 {
+			in.y--
+		} else {
+			n := in.page.insertRows(0, 1, in.eraseCell())
+			in.yMax = min(in.yMax+n, in.page.maxRows-1)
 		}
+	}
+}
 
-		// LF
-		if currentChar == 10 {
-			positionY++
-			positionX = 0
-		}
+// control carries out a C0 control character, the ones without a meaning
+// here are drawn as their glyphs
+func (in *interpreter) control(c byte) {
+	if in.terminal {
+		in.terminalControl(c)
+		return
+	}
 
+	switch c {
+	case 10:
+		// LF
+		in.y++
+		in.x = 0
+	case 13:
+		// CR, always followed by a LF in practice
+	case 9:
 		// tab
-		if currentChar == 9 {
-			positionX += 8
-		}
-
-		// TODO Make this scoped properly
-		const subBreak bool = true
+		in.x += 8
+	case 26:
 		// sub
-		if currentChar == 26 && subBreak {
-			break
-		}
+		in.stopped = true
+	default:
+		in.putChar(c)
+	}
+}
 
-		// ANSi sequence
-		if currentChar == 27 && nextChar == 91 {
-			for ansiSequenceLoop := 0; ansiSequenceLoop < 15; ansiSequenceLoop++ {
-				ansiSequenceChar = inputFileBuffer[loop+2+ansiSequenceLoop]
-
-				// cursor position
-				if ansiSequenceChar == 'H' || ansiSequenceChar == 'f' {
-					// create substring from the sequence's content
-
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-
-					// create sequence content array
-					seqArray = strings.Split(seqGrab, ";")
-					seqArrayCount = len(seqArray)
-
-					if seqArrayCount > 1 {
-						// convert grabbed sequence content to integers
-						seqLine, _ := strconv.Atoi(seqArray[0])
-						seqColumn, _ := strconv.Atoi(seqArray[1])
-
-						// finally set the positions
-						positionY = seqLine - 1
-						positionX = seqColumn - 1
-					} else {
-						// no coordinates specified? we move to the home position
-						positionY = 0
-						positionX = 0
-					}
-					loop += ansiSequenceLoop + 2
-					break
-				}
+// terminalControl carries out a C0 control character on a terminal screen,
+// the way DOS does: the ones without a meaning are drawn as their glyphs
+func (in *interpreter) terminalControl(c byte) {
+	switch c {
+	case 0, 7:
+		// NUL and BEL
+	case 8:
+		// backspace
+		in.x = max(min(in.x, in.page.width-1)-1, 0)
+	case 9:
+		// tab, to the next multiple of 8
+		in.x = min((in.x/8+1)*8, in.page.width-1)
+	case 10:
+		// LF
+		in.lineFeed()
+		in.x = 0
+	case 13:
+		// CR
+		in.x = 0
+	default:
+		in.putChar(c)
+	}
+}
 
-				// cursor up
-				if ansiSequenceChar == 'A' {
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-					//println("A")
+// newCell returns a character drawn with the current colors and attributes
+func (in *interpreter) newCell(char byte) Cell {
+	cell := Cell{
+		Char:  char,
+		Fg:    ansiColor(in.fg),
+		Bg:    ansiColor(in.bg),
+		FgRGB: in.fgTrue,
+		BgRGB: in.bgTrue,
+		Attr:  in.attr,
+	}
 
-					// now get escape sequence's position value
-					seqLine, _ := strconv.Atoi(seqGrab)
+	// the temporary PabloDraw colors win
+	if in.fg24.A > 0 {
+		cell.FgRGB = in.fg24
+	}
+	if in.bg24.A > 0 {
+		cell.BgRGB = in.bg24
+	}
 
-					if seqLine == 0 {
-						seqLine = 1
-					}
+	// with iCE colors blink only picked a bright background, without them
+	// it can't have one
+	if in.icecolors {
+		cell.Attr &^= AttrBlink
+	}
+	cell = darkBlink(cell)
+
+	// CED draws everything black on gray
+	if in.ced {
+		cell.Fg = 0
+		cell.Bg = 7
+		cell.FgRGB = color.RGBA{}
+		cell.BgRGB = color.RGBA{}
+	}
 
-					positionY = positionY - seqLine
+	return cell
+}
 
-					loop += ansiSequenceLoop + 2
-					break
-				}
+// eraseCell returns what erased cells are filled with: blanks in the
+// current background color
+func (in *interpreter) eraseCell() Cell {
+	cell := in.newCell(' ')
+	cell.Attr = 0
+	return cell
+}
 
-				// cursor down
-				if ansiSequenceChar == 'B' {
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-					//println("B")
+// putChar writes a character at the cursor and advances it
+func (in *interpreter) putChar(char byte) {
+	// wrap here too, REP writes several characters in a row
+	if in.x >= in.page.width {
+		in.lineFeed()
+		in.x = 0
+	}
 
-					// now get escape sequence's position value
-					seqLine, _ := strconv.Atoi(seqGrab)
+	// record number of columns and lines used
+	if in.x > in.xMax {
+		in.xMax = in.x
+	}
 
-					if seqLine == 0 {
-						seqLine = 1
-					}
+	if in.y > in.yMax && in.y < in.page.maxRows {
+		in.yMax = in.y
+	}
 
-					positionY = positionY + seqLine
+	if in.amiga && (char == 12 || char == 13) {
+		return
+	}
 
-					loop += ansiSequenceLoop + 2
-					break
-				}
+	in.page.set(in.x, in.y, in.newCell(char))
 
-				// cursor forward
-				if ansiSequenceChar == 'C' {
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-					//println("C")
+	in.lastChar = char
+	in.hasLastChar = true
 
-					// now get escape sequence's position value
-					seqColumn, _ := strconv.Atoi(seqGrab)
+	in.fg24 = color.RGBA{}
+	in.bg24 = color.RGBA{}
 
-					if seqColumn == 0 {
-						seqColumn = 1
-					}
+	in.x++
+}
 
-					positionX = positionX + seqColumn
+// csi carries out a control sequence
+func (in *interpreter) csi(e *Event) {
+	switch e.Char {
+	case 'H', 'f':
+		// cursor position
+		in.y = e.Param(0, 1) - 1
+		in.x = e.Param(1, 1) - 1
+
+	case 'A':
+		// cursor up
+		in.y = max(in.y-e.Param(0, 1), 0)
+
+	case 'B':
+		// cursor down
+		in.y += e.Param(0, 1)
+
+	case 'C':
+		// cursor forward
+		in.x = min(in.x+e.Param(0, 1), in.page.width)
+
+	case 'D':
+		// cursor backward
+		in.x = max(in.x-e.Param(0, 1), 0)
+
+	case 'E', 'F':
+		// cursor next line and previous line
+		if e.Char == 'E' {
+			in.y += e.Param(0, 1)
+		} else {
+			in.y = max(in.y-e.Param(0, 1), 0)
+		}
+		in.x = 0
+
+	case 'G':
+		// cursor horizontal absolute
+		in.x = min(e.Param(0, 1)-1, in.page.width-1)
+
+	case 'd':
+		// vertical position absolute
+		in.y = e.Param(0, 1) - 1
+
+	case 's':
+		// save cursor position
+		in.savedX, in.savedY = in.x, in.y
+
+	case 'u':
+		// restore cursor position
+		in.x, in.y = in.savedX, in.savedY
+
+	case 'J':
+		// erase display
+		switch e.Param(0, 0) {
+		case 0:
+			// from the cursor to the end
+			in.page.erase(in.x, in.page.width, in.y, in.eraseCell())
+			in.page.eraseRows(in.y+1, in.page.maxRows, in.eraseCell())
+		case 1:
+			// from the top to the cursor
+			in.page.eraseRows(0, in.y, in.eraseCell())
+			in.page.erase(0, in.x+1, in.y, in.eraseCell())
+		case 2:
+			// start over on a fresh page
+			in.x, in.y = 0, 0
+			in.xMax, in.yMax = 0, 0
+			in.page.clear()
+
+			// a terminal clears to the current background
+			if in.terminal {
+				in.page.eraseRows(0, in.page.height, in.eraseCell())
+			}
+		}
 
-					if positionX > 80 {
-						positionX = 80
-					}
+	case 'K':
+		// erase line
+		switch e.Param(0, 0) {
+		case 0:
+			in.page.erase(in.x, in.page.width, in.y, in.eraseCell())
+		case 1:
+			in.page.erase(0, in.x+1, in.y, in.eraseCell())
+		case 2:
+			in.page.erase(0, in.page.width, in.y, in.eraseCell())
+		}
 
-					loop += ansiSequenceLoop + 2
-					break
-				}
+	case 'X':
+		// erase characters
+		in.page.erase(in.x, in.x+min(e.Param(0, 1), in.page.width), in.y, in.eraseCell())
 
-				// cursor backward
-				if ansiSequenceChar == 'D' {
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-					//println("D")
+	case 'P':
+		// delete characters
+		in.page.deleteChars(in.x, in.y, e.Param(0, 1), in.eraseCell())
 
-					// now get escape sequence's content length
-					seqColumn, _ := strconv.Atoi(seqGrab)
+	case '@':
+		// insert characters
+		in.page.insertChars(in.x, in.y, e.Param(0, 1), in.eraseCell())
 
-					if seqColumn == 0 {
-						seqColumn = 1
-					}
+	case 'L', 'M', 'S', 'T':
+		// insert and delete lines at the cursor, scroll the page up and
+		// down: files have no screen to scroll, the page scrolls from its top
+		count := e.Param(0, 1)
 
-					positionX = positionX - seqColumn
+		row := in.y
+		if e.Char == 'S' || e.Char == 'T' {
+			row = 0
+		}
 
-					if positionX < 0 {
-						positionX = 0
-					}
+		if e.Char == 'L' || e.Char == 'T' {
+			count = in.page.insertRows(row, count, in.eraseCell())
 
-					loop += ansiSequenceLoop + 2
-					break
-				}
+			if row <= in.yMax {
+				in.yMax = min(in.yMax+count, in.page.maxRows-1)
+			}
+		} else {
+			in.page.deleteRows(row, count, in.eraseCell())
 
-				// save cursor position
-				if ansiSequenceChar == 's' {
-					savedPositionY = positionY
-					savedPositionX = positionX
-					//println("s")
+			if row <= in.yMax {
+				in.yMax = max(in.yMax-count, row-1, 0)
+			}
+		}
 
-					loop += ansiSequenceLoop + 2
-					break
-				}
+	case 'b':
+		// repeat the last character, no more than the rest of the page
+		count := min(e.Param(0, 1), in.page.width*in.page.maxRows)
 
-				// restore cursor position
-				if ansiSequenceChar == 'u' {
-					positionY = savedPositionY
-					positionX = savedPositionX
-					//println("u")
+		for i := 0; in.hasLastChar && i < count && in.y < in.page.maxRows; i++ {
+			in.putChar(in.lastChar)
+		}
 
-					loop += ansiSequenceLoop + 2
-					break
-				}
+	case 'm':
+		// set graphics mode
+		in.sgr(e.Params, e.Sub)
 
-				// erase display
-				if ansiSequenceChar == 'J' {
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-					//println("J")
-					// convert grab to an integer
-					eraseDisplayInt, _ := strconv.Atoi(seqGrab)
-
-					if eraseDisplayInt == 2 {
-						positionX = 0
-						positionY = 0
-
-						positionXMax = 0
-						positionYMax = 0
-
-						// reset ansi buffer
-						ansiBuffer = nil
-						structIndex = 0
-					}
-					loop += ansiSequenceLoop + 2
-					break
-				}
+	case 't':
+		// 24-bit ANSI support
+		// Sets a temporary color for this sequence that overrides the foreground or background colors
+		if len(e.Params) == 4 {
+			rgb := color.RGBA{uint8(e.Params[1]), uint8(e.Params[2]), uint8(e.Params[3]), 255}
 
-				// set graphics mode
-				if ansiSequenceChar == 'm' {
-					//println("m")
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-
-					// create sequence content array
-					seqArray = strings.Split(seqGrab, ";")
-					seqArrayCount = len(seqArray)
-
-					//					fmt.Printf("SEQARRAY: %v", seqArray)
-					// a loophole in limbo
-					for seqGraphicsLoop := 0; seqGraphicsLoop < seqArrayCount; seqGraphicsLoop++ {
-						// convert split content value to integer
-						seqValue, _ := strconv.Atoi(seqArray[seqGraphicsLoop])
-
-						//println("SEQVALUE", seqValue)
-
-						if seqValue == 0 {
-							colorBackground = 0
-							colorForeground = 7
-							bold = false
-							underline = false
-							italics = false
-							blink = false
-						}
-
-						if seqValue == 1 {
-							if !workbench {
-								colorForeground += 8
-							}
-							bold = true
-						}
-
-						if seqValue == 3 {
-							italics = true
-						}
-
-						if seqValue == 4 {
-							underline = true
-						}
-
-						if seqValue == 5 {
-							if !workbench {
-								colorBackground += 8
-							}
-							blink = true
-						}
-
-						if seqValue > 29 && seqValue < 38 {
-							colorForeground = seqValue - 30
-
-							if bold {
-								colorForeground += 8
-							}
-						}
-
-						if seqValue > 39 && seqValue < 48 {
-							colorBackground = seqValue - 40
-
-							if blink && icecolors {
-								colorBackground += 8
-							}
-						}
-					}
-
-					loop += ansiSequenceLoop + 2
-					break
-				}
+			if e.Params[0] == 0 {
+				in.bg24 = rgb
+			} else if e.Params[0] == 1 {
+				in.fg24 = rgb
+			}
+		}
 
-				// 24-bit ANSI support
-				// Sets a temporary color for this sequence that overrides the foreground or background colors
-				if ansiSequenceChar == 't' {
-					// create substring from the sequence's content
-					seqGrab = string(inputFileBuffer[loop+2 : loop+2+ansiSequenceLoop])
-
-					// create sequence content array
-					seqArray = strings.Split(seqGrab, ";")
-					seqArrayCount = len(seqArray)
-
-					if seqArrayCount == 4 {
-						r, _ := strconv.Atoi(seqArray[1])
-						g, _ := strconv.Atoi(seqArray[2])
-						b, _ := strconv.Atoi(seqArray[3])
-
-						if seqArray[0] == "0" {
-							bg24 = color.RGBA{uint8(r), uint8(g), uint8(b), 255}
-						} else if seqArray[0] == "1" {
-							fg24 = color.RGBA{uint8(r), uint8(g), uint8(b), 255}
-						}
-					}
-
-					loop += ansiSequenceLoop + 2
-					break
-				}
+		// anything else, like cursor (de)activation (Amiga ANSi) and set
+		// mode and reset mode sequences, is skipped
+	}
+}
 
-				// cursor (de)activation (Amiga ANSi)
-				if ansiSequenceChar == 'p' {
-					loop += ansiSequenceLoop + 2
-					break
-				}
+// sgr carries out a set graphics mode sequence. Sub-parameters are only
+// read by the extended colors, other parameters ignore them.
+func (in *interpreter) sgr(params []int, sub [][]int) {
+	// no parameters is a reset
+	if len(params) == 0 {
+		params = []int{0}
+	}
 
-				// skipping set mode and reset mode sequences
-				if ansiSequenceChar == 'h' || ansiSequenceChar == 'l' {
-					loop += ansiSequenceLoop + 2
-					break
-				}
+	// a loophole in limbo
+	for i := 0; i < len(params); i++ {
+		seqValue := params[i]
+
+		switch {
+		case seqValue == 0:
+			in.bg = 0
+			in.fg = 7
+			in.attr = 0
+			in.fgTrue = color.RGBA{}
+			in.bgTrue = color.RGBA{}
+
+		case seqValue == 1:
+			if !in.workbench && in.fg < 8 {
+				in.fg += 8
 			}
-		} else if currentChar != 10 && currentChar != 13 && currentChar != 9 {
-			// record number of columns and lines used
-			if positionX > positionXMax {
-				positionXMax = positionX
+			in.attr |= AttrBold
+
+		case seqValue == 2:
+			in.attr |= AttrFaint
+
+		case seqValue == 3:
+			in.attr |= AttrItalic
+
+		case seqValue == 4:
+			in.attr |= AttrUnderline
+
+		case seqValue == 5:
+			if !in.workbench && in.bg < 8 {
+				in.bg += 8
 			}
+			in.attr |= AttrBlink
+
+		case seqValue == 7:
+			in.attr |= AttrReverse
+
+		case seqValue == 8:
+			in.attr |= AttrConceal
 
-			if positionY > positionYMax {
-				positionYMax = positionY
+		case seqValue == 9:
+			in.attr |= AttrStrike
+
+		case seqValue == 22:
+			// normal intensity, undoing the bright color bold picked
+			if in.attr&AttrBold != 0 && !in.workbench && in.fg > 7 && in.fg < 16 {
+				in.fg -= 8
 			}
+			in.attr &^= AttrBold | AttrFaint
 
-			// write current character in ansiChar structure
-			if !f.isAmigaFont || (currentChar != 12 && currentChar != 13) {
-				var newChar ansiChar
+		case seqValue == 23:
+			in.attr &^= AttrItalic
 
-				newChar.colorBackground = colorBackground
-				newChar.colorForeground = colorForeground
-				newChar.colorFg24 = fg24
-				newChar.colorBg24 = bg24
-				newChar.currentChar = currentChar
-				newChar.bold = bold
-				newChar.italics = italics
-				newChar.underline = underline
-				newChar.positionX = positionX
-				newChar.positionY = positionY
+		case seqValue == 24:
+			in.attr &^= AttrUnderline
 
-				ansiBuffer = append(ansiBuffer, newChar)
+		case seqValue == 25:
+			// blink off, undoing the bright background blink picked
+			if in.attr&AttrBlink != 0 && !in.workbench && in.bg > 7 && in.bg < 16 {
+				in.bg -= 8
+			}
+			in.attr &^= AttrBlink
 
-				fg24 = color.RGBA{0, 0, 0, 0}
-				bg24 = color.RGBA{0, 0, 0, 0}
+		case seqValue == 27:
+			in.attr &^= AttrReverse
 
-				structIndex++
-				positionX++
+		case seqValue == 28:
+			in.attr &^= AttrConceal
+
+		case seqValue == 29:
+			in.attr &^= AttrStrike
+
+		case seqValue > 29 && seqValue < 38:
+			in.fg = seqValue - 30
+			in.fgTrue = color.RGBA{}
+
+			if in.attr&AttrBold != 0 {
+				in.fg += 8
 			}
-		}
-		loop++
-	}
 
-	// allocate image buffer memory
-	positionXMax++
-	positionYMax++
+		case seqValue == 38 || seqValue == 48:
+			// extended colors, from the 256 color palette or 24-bit, in
+			// the colon form or spread over the following parameters
+			var index int
+			var rgb color.RGBA
+			if i < len(sub) && len(sub[i]) > 0 {
+				index, rgb = sgrSubColor(sub[i])
+			} else {
+				var used int
+				index, rgb, used = sgrColor(params[i+1:])
+				i += used
+			}
 
-	if ced {
-		columns = 78
-	}
+			if seqValue == 38 {
+				if index >= 0 {
+					in.fg = index
+					in.fgTrue = color.RGBA{}
+				} else if rgb.A > 0 {
+					in.fgTrue = rgb
+				}
+			} else {
+				if index >= 0 {
+					in.bg = index
+					in.bgTrue = color.RGBA{}
+				} else if rgb.A > 0 {
+					in.bgTrue = rgb
+				}
+			}
 
-	if isDizFile {
-		columns = min(positionXMax, 80)
-	}
+		case seqValue == 39:
+			// default foreground
+			in.fg = 7
+			in.fgTrue = color.RGBA{}
 
-	imANSi = image.NewRGBA(image.Rect(0, 0, columns*bits, (positionYMax)*f.sizeY))
-	black := color.RGBA{0, 0, 0, 255}
+			if in.attr&AttrBold != 0 && !in.workbench {
+				in.fg += 8
+			}
 
-	var colors [16]color.RGBA
+		case seqValue > 39 && seqValue < 48:
+			in.bg = seqValue - 40
+			in.bgTrue = color.RGBA{}
 
-	var cedBackground, cedForeground color.RGBA
+			if in.attr&AttrBlink != 0 && in.icecolors {
+				in.bg += 8
+			}
 
-	if ced {
-		cedBackground = color.RGBA{170, 170, 170, 255}
-		cedForeground = color.RGBA{0, 0, 0, 255}
-		draw.Draw(imANSi, imANSi.Bounds(), &image.Uniform{cedBackground}, image.ZP, draw.Src)
-	} else if workbench {
+		case seqValue == 49:
+			// default background
+			in.bg = 0
+			in.bgTrue = color.RGBA{}
 
-		if transparent {
-			draw.Draw(imANSi, imANSi.Bounds(), image.Transparent, image.ZP, draw.Src)
-		} else {
-			draw.Draw(imANSi, imANSi.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
-		}
+		case seqValue > 89 && seqValue < 98:
+			// bright foreground
+			in.fg = seqValue - 90 + 8
+			in.fgTrue = color.RGBA{}
 
-		colors[0] = color.RGBA{170, 170, 170, 255}
-		colors[1] = color.RGBA{0, 0, 0, 255}
-		colors[2] = color.RGBA{255, 255, 255, 255}
-		colors[3] = color.RGBA{102, 136, 187, 255}
-		colors[4] = color.RGBA{0, 0, 255, 255}
-		colors[5] = color.RGBA{255, 0, 255, 255}
-		colors[6] = color.RGBA{0, 255, 255, 255}
-		colors[7] = color.RGBA{255, 255, 255, 255}
-		colors[8] = color.RGBA{170, 170, 170, 255}
-		colors[9] = color.RGBA{0, 0, 0, 255}
-		colors[10] = color.RGBA{255, 255, 255, 255}
-		colors[11] = color.RGBA{102, 136, 187, 255}
-		colors[12] = color.RGBA{0, 0, 255, 255}
-		colors[13] = color.RGBA{255, 0, 255, 255}
-		colors[14] = color.RGBA{0, 255, 255, 255}
-		colors[15] = color.RGBA{255, 255, 255, 255}
-	} else {
-		// Allocate standard ANSi color palette
-		if transparent {
-			draw.Draw(imANSi, imANSi.Bounds(), image.Transparent, image.ZP, draw.Src)
-		} else {
-			draw.Draw(imANSi, imANSi.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
+		case seqValue > 99 && seqValue < 108:
+			// bright background
+			in.bg = seqValue - 100 + 8
+			in.bgTrue = color.RGBA{}
 		}
+	}
+}
 
-		colors[0] = color.RGBA{0, 0, 0, 255}
-		colors[1] = color.RGBA{170, 0, 0, 255}
-		colors[2] = color.RGBA{0, 170, 0, 255}
-		colors[3] = color.RGBA{170, 85, 0, 255}
-		colors[4] = color.RGBA{0, 0, 170, 255}
-		colors[5] = color.RGBA{170, 0, 170, 255}
-		colors[6] = color.RGBA{0, 170, 170, 255}
-		colors[7] = color.RGBA{170, 170, 170, 255}
-		colors[8] = color.RGBA{85, 85, 85, 255}
-		colors[9] = color.RGBA{255, 85, 85, 255}
-		colors[10] = color.RGBA{85, 255, 85, 255}
-		colors[11] = color.RGBA{255, 255, 85, 255}
-		colors[12] = color.RGBA{85, 85, 255, 255}
-		colors[13] = color.RGBA{255, 85, 255, 255}
-		colors[14] = color.RGBA{85, 255, 255, 255}
-		colors[15] = color.RGBA{255, 255, 255, 255}
-	}
-
-	// even more definitions, sigh
-	ansiBufferItems := structIndex
-
-	// render ANSi
-	for loop := 0; loop < ansiBufferItems; loop++ {
-		// grab ANSi char from our structure array
-		colorBackground = ansiBuffer[loop].colorBackground
-		colorForeground = ansiBuffer[loop].colorForeground
-		character = ansiBuffer[loop].currentChar
-		bold = ansiBuffer[loop].bold
-		italics = ansiBuffer[loop].italics
-		underline = ansiBuffer[loop].underline
-		positionX = ansiBuffer[loop].positionX
-		positionY = ansiBuffer[loop].positionY
-
-		if ansiBuffer[loop].colorBg24.A > 0 {
-			bgcolor = ansiBuffer[loop].colorBg24
-		} else {
-			bgcolor = colors[colorBackground]
+// ansiColor converts an ANSi color number, plus 8 for bright colors, to a
+// palette index. Numbers from 16 on are 256 color palette indexes already.
+func ansiColor(c int) int {
+	if c > 15 {
+		return c
+	}
+	return ansiToVGA[c&7] | c&8
+}
+
+// sgrColor parses the arguments following SGR 38 or 48: 5;n picks an index of
+// the 256 color palette, 2;r;g;b a 24-bit color. It returns the index or -1,
+// the color if any, and how many arguments it used.
+func sgrColor(args []int) (int, color.RGBA, int) {
+	if len(args) == 0 {
+		return -1, color.RGBA{}, 0
+	}
+
+	kind := args[0]
+
+	switch {
+	case kind == 5 && len(args) > 1:
+		index := args[1]
+		if index < 0 || index > 255 {
+			return -1, color.RGBA{}, 2
 		}
-		if ansiBuffer[loop].colorFg24.A > 0 {
-			fgcolor = ansiBuffer[loop].colorFg24
-		} else {
-			fgcolor = colors[colorForeground]
+		return index, color.RGBA{}, 2
+	case kind == 2 && len(args) > 3:
+		var rgb [3]uint8
+		for i := range rgb {
+			v := args[1+i]
+			if v < 0 {
+				v = 0
+			} else if v > 255 {
+				v = 255
+			}
+			rgb[i] = uint8(v)
 		}
+		return -1, color.RGBA{rgb[0], rgb[1], rgb[2], 255}, 4
+	case kind == 2 || kind == 5:
+		// cut short, nothing left to apply
+		return -1, color.RGBA{}, len(args)
+	}
 
-		if ced {
-			alDrawChar(imANSi, f.data, bits, f.sizeY,
-				positionX, positionY, cedBackground, cedForeground, character)
-		} else {
-			alDrawChar(imANSi, f.data, bits, f.sizeY,
-				positionX, positionY, bgcolor, fgcolor, character)
-		}
+	return -1, color.RGBA{}, 1
+}
 
+// sgrSubColor parses the sub-parameters of SGR 38 or 48 in the ISO 8613-6
+// form: 5:n, or 2:id:r:g:b with a color space id that is ignored. The id is
+// often left out as in 2:r:g:b. It returns the index or -1 and the color
+// if any.
+func sgrSubColor(sub []int) (int, color.RGBA) {
+	switch {
+	case sub[0] == 5 && len(sub) > 1:
+		if sub[1] > 255 {
+			return -1, color.RGBA{}
+		}
+		return sub[1], color.RGBA{}
+	case sub[0] == 2 && len(sub) > 4:
+		index, rgb, _ := sgrColor(append([]int{2}, sub[2:5]...))
+		return index, rgb
+	case sub[0] == 2 && len(sub) == 4:
+		index, rgb, _ := sgrColor(sub)
+		return index, rgb
 	}
 
-	return imANSi
+	return -1, color.RGBA{}
 }
 
 func min(a, b int) int {
@@ -560,3 +740,13 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func max(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,102 @@
+//  ansi_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"testing"
+)
+
+// decodeANSI decodes data as an ANSi file with the default options
+func decodeANSI(t *testing.T, data string) *Canvas {
+	t.Helper()
+
+	result, err := Decode(context.Background(), bytes.NewReader([]byte(data)), RenderOptions{Format: FormatANSI})
+	if err != nil {
+		t.Fatalf("Decode(%q): %v", data, err)
+	}
+	return result.Canvas
+}
+
+func TestSGRExtendedColors(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+
+	tests := []struct {
+		data  string
+		fg    int
+		fgRGB color.RGBA
+		bgRGB color.RGBA
+	}{
+		{"\x1b[38;2;255;0;0mX", 7, red, color.RGBA{}},
+		{"\x1b[38:2::255:0:0mX", 7, red, color.RGBA{}},
+		{"\x1b[38:2:1:255:0:0mX", 7, red, color.RGBA{}},
+		{"\x1b[38:2:255:0:0mX", 7, red, color.RGBA{}},
+		{"\x1b[48:2::1:2:3mX", 7, color.RGBA{}, color.RGBA{1, 2, 3, 255}},
+		{"\x1b[38;5;196mX", 196, color.RGBA{}, color.RGBA{}},
+		{"\x1b[38:5:196mX", 196, color.RGBA{}, color.RGBA{}},
+		// sub-parameters don't turn into codes of their own
+		{"\x1b[38:2::255:0:0:0:0mX", 7, red, color.RGBA{}},
+		{"\x1b[1;38:2::255:0:0mX", 15, red, color.RGBA{}},
+	}
+
+	for _, tt := range tests {
+		cell := decodeANSI(t, tt.data).At(0, 0)
+		if cell.Char != 'X' || cell.Fg != tt.fg || cell.FgRGB != tt.fgRGB || cell.BgRGB != tt.bgRGB {
+			t.Errorf("%q: got %+v, want Fg %d FgRGB %v BgRGB %v", tt.data, cell, tt.fg, tt.fgRGB, tt.bgRGB)
+		}
+	}
+}
+
+func TestInsertLines(t *testing.T) {
+	tests := []struct {
+		data   string
+		height int
+		y      int // the row X ends up on
+	}{
+		{"X\x1b[H\x1b[2L", 3, 2},
+		{"A\r\nX\x1b[H\x1b[B\x1b[L", 3, 2},
+		{"X\x1b[H\x1b[T", 2, 1},
+		{"X\x1bM", 2, 1},
+		// a page takes no more rows than the file has bytes
+		{"X\x1b[H\x1b[65535L\x1b[65535L", 21, 20},
+	}
+
+	for _, tt := range tests {
+		canvas := decodeANSI(t, tt.data)
+		if canvas.Height != tt.height {
+			t.Errorf("%q: height %d, want %d", tt.data, canvas.Height, tt.height)
+			continue
+		}
+		if cell := canvas.At(0, tt.y); cell.Char != 'X' {
+			t.Errorf("%q: got %q at row %d, want 'X'", tt.data, cell.Char, tt.y)
+		}
+	}
+}
+
+func TestCursorRows(t *testing.T) {
+	tests := []struct {
+		data   string
+		height int
+	}{
+		{"\x1b[5Bx", 6},
+		// moving the cursor far down adds no rows beyond the data
+		{"\x1b[50000Bx", 1},
+		{"x\x1b[50000B", 1},
+		{"x\x1b[50000B\x1b[K", 1},
+	}
+
+	for _, tt := range tests {
+		if canvas := decodeANSI(t, tt.data); canvas.Height != tt.height {
+			t.Errorf("%q: height %d, want %d", tt.data, canvas.Height, tt.height)
+		}
+	}
+}
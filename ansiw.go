@@ -0,0 +1,427 @@
+//  ansiw.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ansiColumns is where the ANSi reader wraps lines
+const ansiColumns = 80
+
+// ANSIOptions are the choices of EncodeANSI
+type ANSIOptions struct {
+	IceColors bool   // bright backgrounds instead of blinking text, read back with iCE colors
+	TrueColor bool   // 24-bit SGR for colors outside of the ANSi palette (default: nearest of 16)
+	Title     string // SAUCE title
+	Author    string // SAUCE author
+	Group     string // SAUCE group
+}
+
+// ansiAttrs are the attributes EncodeANSI sets with SGR, other than blink
+// and faint. Bold only brightens the foreground.
+var ansiAttrs = []struct {
+	attr    Attr
+	on, off int
+}{
+	{AttrItalic, 3, 23},
+	{AttrUnderline, 4, 24},
+	{AttrReverse, 7, 27},
+	{AttrConceal, 8, 28},
+	{AttrStrike, 9, 29},
+}
+
+// EncodeANSI writes the canvas as ANSi, the way the ANSi reader reads it
+// back: each change of colors and attributes with the shortest SGR sequence
+// that makes it, runs of blanks skipped with a cursor forward and lines that
+// fill all 80 columns wrapped without a line break. A SAUCE record with the
+// size of the canvas follows the data.
+//
+// Bright foregrounds are written with bold and bright backgrounds with
+// blink, so the file needs opts.IceColors when it is read. Colors that the
+// ANSi palette lacks become the nearest of its 16 colors, or 24-bit colors
+// with opts.TrueColor. Characters the reader takes as controls, line feed,
+// carriage return, tab, SUB and ESC, are written as spaces.
+func (c *Canvas) EncodeANSI(w io.Writer, opts ANSIOptions) error {
+	if c.Width < 1 || c.Width > ansiColumns || c.Height > ansiMaxRows {
+		return formatError(FormatANSI, 0, ErrBadOption)
+	}
+
+	e := &ansiEncoder{
+		canvas:  c,
+		opts:    opts,
+		palette: ansiPalette(nil, nil),
+		in:      newInterpreter("", opts.IceColors, false),
+		bw:      bufio.NewWriter(w),
+	}
+
+	for y := 0; y < c.Height; y++ {
+		row := c.Cells[y*c.Width : (y+1)*c.Width]
+
+		// blanks at the end of a line are left out, but the last line needs
+		// a character to count
+		end := len(row)
+		for end > 0 && e.blank(row[end-1]) {
+			end--
+		}
+		if y == c.Height-1 && end == 0 {
+			end = 1
+		}
+
+		for x := 0; x < end; {
+			n := 0
+			for x+n < end && e.blank(row[x+n]) {
+				n++
+			}
+
+			if n > 0 && x+n < end && e.skip(n) {
+				e.size += e.write("\x1b[" + ansiCount(n) + "C")
+				x += n
+				continue
+			}
+
+			for i := 0; i < max(n, 1); i++ {
+				e.put(row[x+i])
+			}
+			x += max(n, 1)
+		}
+
+		// the reader wraps a full line by itself
+		if end < ansiColumns && y < c.Height-1 {
+			e.size += e.write("\r\n")
+		}
+	}
+
+	if e.in.attr != 0 || e.in.fg != 7 || e.in.bg != 0 || e.in.fgTrue.A > 0 || e.in.bgTrue.A > 0 {
+		e.size += e.write("\x1b[0m")
+	}
+
+	if err := WriteSauce(e.bw, Sauce{Sauceinf: e.sauce()}); err != nil {
+		return err
+	}
+
+	return e.bw.Flush()
+}
+
+// ansiEncoder keeps the state of EncodeANSI. The reader's own interpreter
+// follows along, so that sequences are only written when they change what
+// the next character looks like.
+type ansiEncoder struct {
+	canvas  *Canvas
+	opts    ANSIOptions
+	palette Palette // the one the reader draws with
+	in      *interpreter
+	bw      *bufio.Writer
+	size    int // bytes written, for the SAUCE record
+}
+
+// ansiPen is the look of a cell in the reader: colors as palette indexes,
+// unless the 24-bit ones are set, and the attributes
+type ansiPen struct {
+	fg, bg       int
+	fgRGB, bgRGB color.RGBA
+	attr         Attr
+}
+
+// write writes s and returns its length
+func (e *ansiEncoder) write(s string) int {
+	e.bw.WriteString(s)
+	return len(s)
+}
+
+// put writes a cell, changing colors and attributes first if needed
+func (e *ansiEncoder) put(cell Cell) {
+	pen := e.pen(cell)
+
+	// a blank looks the same with any pen that leaves it blank
+	current := e.current()
+	if !(e.blank(cell) && current.blank()) && current != pen {
+		params := e.sgrParams(pen)
+		e.in.sgr(params, nil)
+		e.size += e.write(ansiSGR(params))
+	}
+
+	char := cell.Char
+	switch char {
+	case 9, 10, 13, 26, 27:
+		char = ' '
+	case 12:
+		if e.canvas.Font.Amiga {
+			char = ' '
+		}
+	}
+
+	e.bw.WriteByte(char)
+	e.size++
+}
+
+// skip tells whether a run of n blanks is shorter as a cursor forward than
+// written out
+func (e *ansiEncoder) skip(n int) bool {
+	written := n
+	if !e.current().blank() {
+		written += len(ansiSGR(e.sgrParams(ansiPen{fg: 7})))
+	}
+
+	return len("\x1b["+ansiCount(n)+"C") < written
+}
+
+// blank tells whether a cell looks like the ones the reader leaves alone
+func (e *ansiEncoder) blank(cell Cell) bool {
+	return cell.Char == ' ' && e.pen(cell).blank()
+}
+
+func (p ansiPen) blank() bool {
+	return p.bg == 0 && p.bgRGB.A == 0 && p.attr&(AttrReverse|AttrUnderline|AttrStrike) == 0
+}
+
+// pen returns the look a cell should have in the reader
+func (e *ansiEncoder) pen(cell Cell) ansiPen {
+	pen := ansiPen{attr: cell.Attr & (AttrFaint | AttrItalic | AttrUnderline | AttrReverse | AttrConceal | AttrStrike)}
+
+	// the reader only keeps blink without iCE colors
+	if !e.opts.IceColors {
+		pen.attr |= cell.Attr & AttrBlink
+	}
+
+	pen.fg, pen.fgRGB = e.color(cell.Fg, cell.FgRGB)
+	pen.bg, pen.bgRGB = e.color(cell.Bg, cell.BgRGB)
+
+	// blinking cells have dark backgrounds in the reader too, see darkBlink
+	if pen.attr&AttrBlink != 0 && pen.bgRGB.A == 0 && pen.bg > 7 && pen.bg < 16 {
+		pen.bg -= 8
+	}
+
+	return pen
+}
+
+// color returns a color of the canvas as a palette index of the reader or
+// as a 24-bit color
+func (e *ansiEncoder) color(index int, rgb color.RGBA) (int, color.RGBA) {
+	if rgb.A == 0 {
+		rgb = e.canvas.paletteColor(index)
+		if index < len(e.palette) && e.palette[index] == rgb {
+			return index, color.RGBA{}
+		}
+	}
+
+	if e.opts.TrueColor {
+		return 0, rgb
+	}
+	return e.palette.nearest(rgb, 0, 16), color.RGBA{}
+}
+
+// current returns the look the next character gets
+func (e *ansiEncoder) current() ansiPen {
+	return cellPen(e.in.newCell(' '))
+}
+
+// cellPen returns the look of a cell of the reader
+func cellPen(cell Cell) ansiPen {
+	pen := ansiPen{fg: cell.Fg, bg: cell.Bg, fgRGB: cell.FgRGB, bgRGB: cell.BgRGB, attr: cell.Attr &^ AttrBold}
+
+	// indexes don't matter under 24-bit colors
+	if pen.fgRGB.A > 0 {
+		pen.fg = 0
+	}
+	if pen.bgRGB.A > 0 {
+		pen.bg = 0
+	}
+
+	return pen
+}
+
+// sgrParams returns the shortest SGR parameters that give the next
+// character pen. They either change the current colors and attributes or
+// start over from a reset, and use the classic codes of DOS viewers where
+// they can.
+func (e *ansiEncoder) sgrParams(pen ansiPen) []int {
+	var best []int
+
+	for _, reset := range []bool{false, true} {
+		for _, classic := range []bool{true, false} {
+			trial := *e.in
+
+			var params []int
+			if reset {
+				params = []int{0}
+				trial.sgr(params, nil)
+			}
+
+			params = trial.penParams(params, pen, classic)
+			if cellPen(trial.newCell(' ')) != pen {
+				continue
+			}
+
+			if best == nil || len(ansiSGR(params)) < len(ansiSGR(best)) {
+				best = params
+			}
+		}
+	}
+
+	return best
+}
+
+// penParams appends the SGR parameters that change the colors and
+// attributes to pen, carrying them out as it goes. The classic codes are
+// bold with 30 to 37 for the 16 foreground colors, blink with 40 to 47 for
+// the background ones, otherwise the codes of the 256 color palette are
+// used. They can't make every combination, the result has to be checked.
+func (in *interpreter) penParams(params []int, pen ansiPen, classic bool) []int {
+	add := func(values ...int) {
+		params = append(params, values...)
+		in.sgr(values, nil)
+	}
+
+	// attributes to turn off, 22 ends bold and faint together
+	if in.attr&AttrFaint != 0 && pen.attr&AttrFaint == 0 {
+		add(22)
+	}
+	for _, a := range ansiAttrs {
+		if in.attr&a.attr != 0 && pen.attr&a.attr == 0 {
+			add(a.off)
+		}
+	}
+
+	// foreground
+	if fg := cellPen(in.newCell(' ')); fg.fg != pen.fg || fg.fgRGB != pen.fgRGB {
+		switch {
+		case pen.fgRGB.A > 0:
+			add(38, 2, int(pen.fgRGB.R), int(pen.fgRGB.G), int(pen.fgRGB.B))
+
+		case classic && pen.fg < 16:
+			n := ansiColor(pen.fg)
+
+			// bold brightens the color, ending it darkens it again
+			if n > 7 && in.attr&AttrBold == 0 {
+				add(1)
+			} else if n < 8 && in.attr&AttrBold != 0 {
+				add(22)
+			}
+			if fg := cellPen(in.newCell(' ')); fg.fg != pen.fg || fg.fgRGB.A > 0 {
+				add(30 + n&7)
+			}
+
+		default:
+			add(38, 5, ansiColor(pen.fg))
+		}
+	}
+
+	// background, along with blink which brightens it
+	blink := in.attr&AttrBlink != 0
+	wantBlink := pen.attr&AttrBlink != 0
+	if bg := cellPen(in.newCell(' ')); bg.bg != pen.bg || bg.bgRGB != pen.bgRGB || (!in.icecolors && blink != wantBlink) {
+		n := ansiColor(pen.bg)
+
+		switch {
+		case classic && pen.bgRGB.A == 0 && pen.bg < 16 && in.icecolors:
+			// blink picks the bright half of the colors
+			if n > 7 && !blink {
+				add(5)
+			} else if n < 8 && blink {
+				add(25)
+			}
+			if bg := cellPen(in.newCell(' ')); bg.bg != pen.bg || bg.bgRGB.A > 0 {
+				add(40 + n&7)
+			}
+
+		case classic && pen.bgRGB.A == 0 && pen.bg < 8 && wantBlink:
+			// the background stays dark whichever color blink comes after
+			if !blink {
+				add(5)
+			}
+			if bg := cellPen(in.newCell(' ')); bg.bg != pen.bg || bg.bgRGB.A > 0 {
+				add(40 + n)
+			}
+
+		default:
+			if !in.icecolors && blink != wantBlink {
+				if wantBlink {
+					add(5)
+				} else {
+					add(25)
+				}
+			}
+
+			if bg := cellPen(in.newCell(' ')); bg.bg != pen.bg || bg.bgRGB != pen.bgRGB {
+				if pen.bgRGB.A > 0 {
+					add(48, 2, int(pen.bgRGB.R), int(pen.bgRGB.G), int(pen.bgRGB.B))
+				} else if classic && pen.bg < 8 {
+					add(40 + n)
+				} else {
+					add(48, 5, n)
+				}
+			}
+		}
+	}
+
+	// attributes to turn on
+	if in.attr&AttrFaint == 0 && pen.attr&AttrFaint != 0 {
+		add(2)
+	}
+	for _, a := range ansiAttrs {
+		if in.attr&a.attr == 0 && pen.attr&a.attr != 0 {
+			add(a.on)
+		}
+	}
+
+	return params
+}
+
+// sauce returns the SAUCE record of the written data
+func (e *ansiEncoder) sauce() SauceInfo {
+	info := SauceInfo{
+		FileSize: int32(e.size),
+		Tinfo1:   uint16(e.canvas.Width),
+		Tinfo2:   uint16(e.canvas.Height),
+	}
+
+	info.SetType(FileANSi)
+	sauceString(info.Title[:], e.opts.Title)
+	sauceString(info.Author[:], e.opts.Author)
+	sauceString(info.Group[:], e.opts.Group)
+	info.SetTime(time.Now())
+
+	// iCE colors and the letter spacing
+	flags := FlagLetter8
+	if e.canvas.Bits == 9 {
+		flags = FlagLetter9
+	}
+	if e.opts.IceColors {
+		flags |= FlagNonBlink
+	}
+	info.Flags = byte(flags)
+
+	return info
+}
+
+// ansiSGR returns the SGR sequence of params
+func ansiSGR(params []int) string {
+	seq := "\x1b["
+	for i, p := range params {
+		if i > 0 {
+			seq += ";"
+		}
+		seq += strconv.Itoa(p)
+	}
+	return seq + "m"
+}
+
+// ansiCount returns the count parameter of a sequence, left out for 1
+func ansiCount(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
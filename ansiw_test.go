@@ -0,0 +1,151 @@
+//  ansiw_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// encodeANSI writes c as ANSi and decodes it again as the reader would
+func encodeANSI(t *testing.T, c *Canvas, opts ANSIOptions) ([]byte, *Canvas) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := c.EncodeANSI(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes(), decodeData(t, buf.Bytes(), RenderOptions{Format: FormatANSI, IceColors: opts.IceColors})
+}
+
+// sameCells compares the cells of two canvases, bold only brightens the
+// foreground so it may differ
+func sameCells(t *testing.T, name string, got, want *Canvas) {
+	t.Helper()
+
+	if got.Width != want.Width || got.Height != want.Height {
+		t.Errorf("%s: got %dx%d, want %dx%d", name, got.Width, got.Height, want.Width, want.Height)
+		return
+	}
+
+	for i, cell := range want.Cells {
+		g := got.Cells[i]
+		g.Attr &^= AttrBold
+		cell.Attr &^= AttrBold
+		if g != cell {
+			t.Errorf("%s: cell %d,%d is %+v, want %+v", name, i%want.Width, i/want.Width, g, cell)
+		}
+	}
+}
+
+func TestEncodeANSI(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		ice  bool
+	}{
+		{"colors", "\x1b[1;31mred \x1b[0;44;33myellow on blue\x1b[0m plain\r\n\x1b[92mbright \x1b[38;5;208morange", false},
+		{"attributes", "\x1b[3mitalic\x1b[4munder\x1b[0;7mrev\x1b[0;9mstrike\x1b[0;2mfaint\x1b[0;8mhidden", false},
+		{"blink", "\x1b[5;44mblink\x1b[25m still blue \x1b[5;1;37mbright\x1b[0m done", false},
+		{"iCE colors", "\x1b[5;44mbright\x1b[0;45mdark \x1b[105mbright", true},
+		{"blanks", "a\x1b[30Cb\x1b[44m   \x1b[0m   c", false},
+		{"full lines", strings.Repeat("x", 80) + strings.Repeat("y", 80) + "z", false},
+		{"erased", "\x1b[41m\x1b[K\r\n\x1b[0mnext", false},
+	}
+
+	for _, tt := range tests {
+		want := decodeData(t, []byte(tt.data), RenderOptions{Format: FormatANSI, IceColors: tt.ice})
+		_, got := encodeANSI(t, want, ANSIOptions{IceColors: tt.ice})
+		sameCells(t, tt.name, got, want)
+	}
+}
+
+func TestEncodeANSIColors(t *testing.T) {
+	orange := color.RGBA{255, 128, 0, 255}
+
+	c := NewCanvas(3, 1, SelectFont("80x25"), ansiPalette(nil, nil))
+	c.Set(0, 0, Cell{Char: 'a', Fg: 7, FgRGB: orange})
+	c.Set(1, 0, Cell{Char: 27, Fg: 7, BgRGB: orange})
+	c.Set(2, 0, Cell{Char: 'c', Fg: 200, Bg: 1})
+
+	// 24-bit colors are kept, controls are written as spaces
+	_, got := encodeANSI(t, c, ANSIOptions{TrueColor: true})
+	want := []Cell{
+		{Char: 'a', Fg: 7, FgRGB: orange},
+		{Char: ' ', Fg: 7, BgRGB: orange},
+		{Char: 'c', Fg: 200, Bg: 1},
+	}
+	for x, cell := range want {
+		if g := got.At(x, 0); g != cell {
+			t.Errorf("truecolor: cell %d is %+v, want %+v", x, g, cell)
+		}
+	}
+
+	// or become the nearest of the 16 colors, the 256 color palette is kept
+	_, got = encodeANSI(t, c, ANSIOptions{})
+	for x := 0; x < 2; x++ {
+		g := got.At(x, 0)
+		if g.FgRGB.A != 0 || g.BgRGB.A != 0 || g.Fg > 15 || g.Bg > 15 {
+			t.Errorf("16 colors: cell %d is %+v", x, g)
+		}
+	}
+	if g := got.At(2, 0); g.Fg != 200 {
+		t.Errorf("16 colors: cell 2 is %+v, want Fg 200", g)
+	}
+	if g := got.At(0, 0); got.Palette[g.Fg] != got.Palette[ansiColor(11)] && got.Palette[g.Fg] != got.Palette[ansiColor(3)] {
+		t.Errorf("16 colors: orange became %v", got.Palette[g.Fg])
+	}
+}
+
+func TestEncodeANSISauce(t *testing.T) {
+	c := decodeData(t, []byte("\x1b[44mhello\r\nworld"), RenderOptions{Format: FormatANSI})
+	c.Bits = 9
+
+	data, _ := encodeANSI(t, c, ANSIOptions{IceColors: true, Title: "Title", Author: "me"})
+
+	record, err := ReadSauceBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := record.Sauceinf
+	columns, lines := info.Size()
+	flags, _ := info.ANSiFlags()
+	if info.Type() != FileANSi || columns != 80 || lines != 2 || !flags.IceColors() || flags.LetterSpacing() != 9 {
+		t.Errorf("got %v %dx%d %v", info.Type(), columns, lines, flags)
+	}
+	if record.Title() != "Title" || record.Author() != "me" {
+		t.Errorf("got title %q author %q", record.Title(), record.Author())
+	}
+	if size := int(info.FileSize); size != len(StripSauce(data, record)) {
+		t.Errorf("FileSize %d, want %d", size, len(StripSauce(data, record)))
+	}
+}
+
+func TestEncodeANSIWidth(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := NewCanvas(81, 1, SelectFont("80x25"), ansiPalette(nil, nil))
+	if err := c.EncodeANSI(&buf, ANSIOptions{}); !errors.Is(err, ErrBadOption) {
+		t.Errorf("81 columns: got %v, want ErrBadOption", err)
+	}
+
+	// narrow canvases read back 80 columns wide
+	c = NewCanvas(10, 2, SelectFont("80x25"), ansiPalette(nil, nil))
+	c.Set(9, 1, Cell{Char: 'x', Fg: 7})
+	_, got := encodeANSI(t, c, ANSIOptions{})
+	if got.Width != 80 || got.Height != 2 || got.At(9, 1).Char != 'x' {
+		t.Errorf("got %dx%d with %q at 9,1", got.Width, got.Height, got.At(9, 1).Char)
+	}
+}
@@ -15,54 +15,42 @@
 
 package goansi
 
-import (
-	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"os"
-)
-
-// Artworx processes inputFileBuffer and generates an image
-func artworx(inputFileBuffer []byte, inputFileSize int64) image.Image {
-	// some type declarations
-	var f font
-
-	// libgd image pointers
-	var imADF draw.Image
+import "context"
 
-	imADF = image.NewRGBA(image.Rect(0, 0, 640, (((int(inputFileSize)-192-4096-1)/2)/80)*16))
+// adfColors are the entries of the 64 color ADF palette that the 16 text
+// mode colors use
+var adfColors = [16]int{0, 1, 2, 3, 4, 5, 20, 7, 56, 57, 58, 59, 60, 61, 62, 63}
 
-	if imADF == nil {
-		fmt.Printf("\nError, can't allocate buffer image memory.\n\n")
-		os.Exit(6)
+// Artworx processes inputFileBuffer and generates a canvas
+func artworx(ctx context.Context, inputFileBuffer []byte, inputFileSize int64) (*Canvas, error) {
+	// version byte, 64 color palette and 256 character font
+	if inputFileSize < adfHeaderSize {
+		return nil, formatError(FormatArtworx, int(inputFileSize), ErrTruncated)
 	}
 
-	black := color.RGBA{0, 0, 0, 255}
-	draw.Draw(imADF, imADF.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
+	// some type declarations
+	var f Font
 
-	// ADF color palette array
-	adfColors := [16]int{0, 1, 2, 3, 4, 5, 20, 7, 56, 57, 58, 59, 60, 61, 62, 63}
-	var colors [16]color.RGBA
-	f.data = inputFileBuffer[193 : 193+4096]
+	colors := make(Palette, 16)
+	f.Data = append([]byte(nil), inputFileBuffer[193:193+4096]...)
+	f.Width = 8
+	f.Height = 16
 
 	var loop int
-	var index int
 	// process ADF palette
+	vgaColors := readVGAPalette(inputFileBuffer[1:193], 64)
 	for loop = 0; loop < 16; loop++ {
-		index = (adfColors[loop] * 3) + 1
-
-		colors[loop] = color.RGBA{(inputFileBuffer[index]<<2 | inputFileBuffer[index]>>4),
-			(inputFileBuffer[index+1]<<2 | inputFileBuffer[index+1]>>4),
-			(inputFileBuffer[index+2]<<2 | inputFileBuffer[index+2]>>4), 255}
+		colors[loop] = vgaColors[adfColors[loop]]
 	}
 
+	canvas := NewCanvas(80, ((int(inputFileSize)-192-4096-1)/2)/80, &f, colors)
+
 	// process ADF
 	var positionX, positionY int = 0, 0
 	var character, attribute, colorForeground, colorBackground int
 	loop = 192 + 4096 + 1
 
-	for loop < int(inputFileSize) {
+	for loop+1 < int(inputFileSize) {
 		if positionX == 80 {
 			positionX = 0
 			positionY++
@@ -74,11 +62,15 @@ func artworx(inputFileBuffer []byte, inputFileSize int64) image.Image {
 		colorBackground = (attribute & 240) >> 4
 		colorForeground = attribute & 15
 
-		alDrawChar(imADF, f.data, 8, 16, positionX, positionY, colors[colorBackground], colors[colorForeground], byte(character))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		canvas.Set(positionX, positionY, Cell{Char: byte(character), Fg: colorForeground, Bg: colorBackground})
 
 		positionX++
 		loop += 2
 	}
 
-	return imADF
+	return canvas, nil
 }
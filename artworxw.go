@@ -0,0 +1,58 @@
+//  artworxw.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"image/color"
+	"io"
+)
+
+// adfVersion is the version byte ADF files start with
+const adfVersion = 1
+
+// EncodeADF writes the canvas as an Artworx ADF file with its font and the
+// first 16 colors of its palette embedded. ADF files are 80 columns wide
+// with an 8x16 font, narrower canvases are padded with blanks. Blinking
+// text is written as still text.
+func (c *Canvas) EncodeADF(w io.Writer) error {
+	if c.Width > 80 || c.Font.Height != 16 {
+		return formatError(FormatArtworx, 0, ErrBadOption)
+	}
+
+	// the 16 colors go into the EGA palette of 64 colors
+	ega := make(Palette, 64)
+	for i := range ega {
+		// bits 0 to 2 are two thirds of blue, green and red, bits 3 to 5 a third
+		level := func(high, low int) uint8 {
+			return uint8((i>>high&1)*0xAA + (i>>low&1)*0x55)
+		}
+		ega[i] = color.RGBA{level(2, 5), level(1, 4), level(0, 3), 255}
+	}
+	for i, entry := range adfColors {
+		ega[entry] = c.paletteColor(i)
+	}
+	colors := vgaPaletteBytes(ega, 64)
+
+	// the colors as they are read back
+	vga := readVGAPalette(colors, 64)
+	palette := make(Palette, 16)
+	for i, entry := range adfColors {
+		palette[i] = vga[entry]
+	}
+
+	buf := make([]byte, adfHeaderSize)
+	buf[0] = adfVersion
+	copy(buf[1:], colors)
+	copy(buf[1+len(colors):], c.Font.glyphs())
+	buf = append(buf, c.textData(80, palette, false)...)
+
+	_, err := w.Write(buf)
+	return err
+}
@@ -15,60 +15,29 @@
 
 package goansi
 
-import (
-	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"os"
-)
-
-// binary processes inputFileBuffer and generates an image
-func binfile(inputFileBuffer []byte, inputFileSize int64, columns int, fontName string, bits int, icecolors bool) image.Image {
+import "context"
+
+// binary processes inputFileBuffer and generates a canvas
+func binfile(ctx context.Context, inputFileBuffer []byte, inputFileSize int64, columns int, fontName string, bits int, icecolors bool) (*Canvas, error) {
+	if columns < 1 {
+		return nil, formatError(FormatBinary, 0, ErrBadOption)
+	}
+
 	// some type declarations
-	var f font
+	var f Font
 
 	// font selection
 	alSelectFont(&f, fontName)
 
-	// libgd image pointers
-	var imBinary draw.Image
-
-	imBinary = image.NewRGBA(image.Rect(0, 0, columns*bits, (int(inputFileSize)/2)/columns*f.sizeY))
-
-	if imBinary == nil {
-		fmt.Printf("\nError, can't allocate buffer image memory.\n\n")
-		os.Exit(6)
-	}
-
-	black := color.RGBA{0, 0, 0, 255}
-	draw.Draw(imBinary, imBinary.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
-
-	// allocate color palette
-	var colors [16]color.RGBA
-
-	colors[0] = color.RGBA{0, 0, 0, 255}
-	colors[1] = color.RGBA{0, 0, 170, 255}
-	colors[2] = color.RGBA{0, 170, 0, 255}
-	colors[3] = color.RGBA{0, 170, 170, 255}
-	colors[4] = color.RGBA{170, 0, 0, 255}
-	colors[5] = color.RGBA{170, 0, 170, 255}
-	colors[6] = color.RGBA{170, 85, 0, 255}
-	colors[7] = color.RGBA{170, 170, 170, 255}
-	colors[8] = color.RGBA{85, 85, 85, 255}
-	colors[9] = color.RGBA{85, 85, 255, 255}
-	colors[10] = color.RGBA{85, 255, 85, 255}
-	colors[11] = color.RGBA{85, 255, 255, 255}
-	colors[12] = color.RGBA{255, 85, 85, 255}
-	colors[13] = color.RGBA{255, 85, 255, 255}
-	colors[14] = color.RGBA{255, 255, 85, 255}
-	colors[15] = color.RGBA{255, 255, 255, 255}
+	canvas := NewCanvas(columns, (int(inputFileSize)/2)/columns, &f, vgaPalette.clone())
+	canvas.Bits = bits
 
 	// process binary
 	var character, attribute, colorBackground, colorForeground int
 	var loop, positionX, positionY int = 0, 0, 0
 
-	for loop < int(inputFileSize) {
+	// a trailing odd byte can't hold a character/attribute pair, skip it
+	for loop+1 < int(inputFileSize) {
 		if positionX == columns {
 			positionX = 0
 			positionY++
@@ -80,16 +49,24 @@ func binfile(inputFileBuffer []byte, inputFileSize int64, columns int, fontName
 		colorBackground = (attribute & 240) >> 4
 		colorForeground = (attribute & 15)
 
-		if colorBackground > 8 && !icecolors {
+		if colorBackground >= 8 && !icecolors {
 			colorBackground -= 8
 		}
 
-		alDrawChar(imBinary, f.data, bits, f.sizeY,
-			positionX, positionY, colors[colorBackground], colors[colorForeground], byte(character))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		cell := Cell{Char: byte(character), Fg: colorForeground, Bg: colorBackground}
+		if attribute&0x80 != 0 && !icecolors {
+			cell.Attr = AttrBlink
+		}
+
+		canvas.Set(positionX, positionY, cell)
 
 		positionX++
 		loop += 2
 	}
 
-	return imBinary
+	return canvas, nil
 }
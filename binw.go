@@ -0,0 +1,43 @@
+//  binw.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import "io"
+
+// EncodeBin writes the canvas as a BIN file, bare character and attribute
+// pairs. The file holds neither its width, nor its font or palette: it is
+// read back with the columns set to the width of the canvas, and its colors
+// become the nearest of the 16 VGA ones. A canvas with blinking text is
+// read back without iCE colors, which costs it its bright backgrounds, any
+// other canvas with them.
+func (c *Canvas) EncodeBin(w io.Writer) error {
+	if c.Width < 1 {
+		return formatError(FormatBinary, 0, ErrBadOption)
+	}
+
+	_, err := w.Write(c.textData(c.Width, vgaPalette, c.blinks()))
+	return err
+}
+
+// textData returns the cells as character and attribute pairs for a file
+// with the 16 colors of p, row by row. Rows narrower than width are padded
+// with blanks.
+func (c *Canvas) textData(width int, p Palette, blink bool) []byte {
+	data := make([]byte, 0, width*c.Height*2)
+
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < width; x++ {
+			cell := c.At(x, y)
+			data = append(data, cell.Char, c.textAttribute(cell, p, blink))
+		}
+	}
+
+	return data
+}
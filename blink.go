@@ -0,0 +1,55 @@
+//  blink.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"context"
+	"time"
+)
+
+// BlinkDelay is how long blinking text stays visible or hidden: the VGA
+// toggles it every 16 frames of its 70 Hz text mode
+const BlinkDelay = 16 * time.Second * 1000 / 70086
+
+// BlinkAnimation draws the canvas as a two frame animation of the VGA blink
+// cycle. Blinking cells show their text in the first frame and only their
+// dark background in the second.
+func (c *Canvas) BlinkAnimation(ctx context.Context) (*Animation, error) {
+	on, err := c.Draw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	off, err := c.blinkHidden().Draw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	anim := &Animation{Width: on.Bounds().Dx(), Height: on.Bounds().Dy()}
+	anim.add(on, nil, BlinkDelay)
+	anim.add(off, on, BlinkDelay)
+
+	return anim, nil
+}
+
+// blinkHidden returns a copy of the canvas with blinking text hidden
+func (c *Canvas) blinkHidden() *Canvas {
+	hidden := *c
+	hidden.Cells = make([]Cell, len(c.Cells))
+	copy(hidden.Cells, c.Cells)
+
+	for i, cell := range hidden.Cells {
+		if cell.Attr&AttrBlink != 0 {
+			hidden.Cells[i].Attr |= AttrConceal
+		}
+	}
+
+	return &hidden
+}
@@ -0,0 +1,124 @@
+//  blink_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// decodeData decodes data with opts
+func decodeData(t *testing.T, data []byte, opts RenderOptions) *Canvas {
+	t.Helper()
+
+	result, err := Decode(context.Background(), bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("Decode(%q): %v", data, err)
+	}
+	return result.Canvas
+}
+
+func TestBlinkBackgrounds(t *testing.T) {
+	bin := RenderOptions{Format: FormatBinary, Columns: 1}
+	iceBin := RenderOptions{Format: FormatBinary, Columns: 1, IceColors: true}
+	ans := RenderOptions{Format: FormatANSI}
+	iceANS := RenderOptions{Format: FormatANSI, IceColors: true}
+
+	tests := []struct {
+		name  string
+		data  string
+		opts  RenderOptions
+		bg    int
+		blink bool
+	}{
+		{"bin", "X\x94", bin, 1, true},
+		{"bin gray", "X\x84", bin, 0, true},
+		{"bin bright", "X\xf4", bin, 7, true},
+		{"bin iCE", "X\x94", iceBin, 9, false},
+		{"xbin", xbinID + "\x01\x00\x01\x00\x10\x00X\x94", RenderOptions{}, 1, true},
+		{"xbin iCE", xbinID + "\x01\x00\x01\x00\x10\x08X\x94", RenderOptions{}, 9, false},
+		{"pcboard", "@X9FX", RenderOptions{Format: FormatPCBoard}, 1, true},
+		{"blink before the color", "\x1b[5;44mX", ans, 1, true},
+		{"blink after the color", "\x1b[44;5mX", ans, 1, true},
+		{"bright background", "\x1b[5;104mX", ans, 1, true},
+		{"blink off", "\x1b[44;5;25mX", ans, 1, false},
+		{"iCE", "\x1b[5;44mX", iceANS, 9, false},
+		{"iCE after the color", "\x1b[44;5mX", iceANS, 9, false},
+	}
+
+	for _, tt := range tests {
+		cell := decodeData(t, []byte(tt.data), tt.opts).At(0, 0)
+		if cell.Bg != tt.bg || (cell.Attr&AttrBlink != 0) != tt.blink {
+			t.Errorf("%s: got %+v, want Bg %d blinking %v", tt.name, cell, tt.bg, tt.blink)
+		}
+	}
+}
+
+func TestBlinkRoundTrip(t *testing.T) {
+	blue := color.RGBA{0, 0, 170, 255}
+
+	canvas := decodeData(t, []byte(" \x94"), RenderOptions{Format: FormatBinary, Columns: 1})
+
+	var buf bytes.Buffer
+	if err := canvas.EncodeXBin(&buf); err != nil {
+		t.Fatal(err)
+	}
+	xb := decodeData(t, buf.Bytes(), RenderOptions{})
+
+	for name, c := range map[string]*Canvas{"bin": canvas, "xbin": xb} {
+		im, err := c.Draw(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := im.RGBAAt(0, 0); got != blue {
+			t.Errorf("%s: background %v, want %v", name, got, blue)
+		}
+		if cell := c.At(0, 0); cell.Attr&AttrBlink == 0 {
+			t.Errorf("%s: got %+v, want a blinking cell", name, cell)
+		}
+	}
+}
+
+func TestBlinkAnimation(t *testing.T) {
+	canvas := decodeData(t, []byte("\x1b[1;5;44;37m\xdb\x1b[0;31m\xdb"), RenderOptions{Format: FormatANSI})
+
+	anim, err := canvas.BlinkAnimation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(anim.Frames))
+	}
+
+	// a full block shows the foreground, then the dark background
+	on := canvas.Palette[15]
+	off := canvas.Palette[1]
+	if got := color.RGBAModel.Convert(anim.Frames[0].Image.At(4, 4)); got != on {
+		t.Errorf("visible frame: got %v, want %v", got, on)
+	}
+	if got := color.RGBAModel.Convert(anim.Frames[1].Image.At(4, 4)); got != off {
+		t.Errorf("hidden frame: got %v, want %v", got, off)
+	}
+
+	// text that doesn't blink is left as it is, the canvas too
+	still, _ := canvas.CellColors(canvas.At(1, 0))
+	if got := color.RGBAModel.Convert(anim.Frames[0].Image.At(12, 4)); got != still {
+		t.Errorf("visible frame: still text is %v, want %v", got, still)
+	}
+	if hidden := anim.Frames[1].Image; image.Pt(12, 4).In(hidden.Bounds()) {
+		t.Errorf("hidden frame: redraws %v, want only the blinking cell", hidden.Bounds())
+	}
+	if cell := canvas.At(0, 0); cell.Attr&AttrConceal != 0 {
+		t.Errorf("got %+v, want the canvas unchanged", cell)
+	}
+}
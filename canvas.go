@@ -0,0 +1,249 @@
+//  canvas.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// Attr is a set of text attributes
+type Attr uint16
+
+// Text attributes recorded in a Cell
+const (
+	AttrBold Attr = 1 << iota
+	AttrItalic
+	AttrUnderline
+	AttrBlink   // blinking text, only set when iCE colors are off, see darkBlink
+	AttrFaint   // drawn in a dimmer foreground
+	AttrReverse // foreground and background swapped
+	AttrConceal // glyph hidden, drawn in the background color
+	AttrStrike  // line through the middle of the cell
+)
+
+// Cell is a single character position on a Canvas
+type Cell struct {
+	Char  byte       // glyph index into the canvas font
+	Fg    int        // foreground palette index
+	Bg    int        // background palette index
+	FgRGB color.RGBA // 24-bit foreground, used instead of Fg unless its alpha is 0
+	BgRGB color.RGBA // 24-bit background, used instead of Bg unless its alpha is 0
+	Attr  Attr
+}
+
+// cellWrite is a cell written at a position, for decoders that only learn
+// the size of their canvas at the end
+type cellWrite struct {
+	x, y int
+	cell Cell
+}
+
+// blankCell is what a text mode screen is cleared to
+var blankCell = Cell{Char: ' ', Fg: 7}
+
+// darkBlink returns cell with the background a blinking cell has on a VGA
+// screen with blinking enabled, where the high bit of the background color
+// makes the text blink instead: one of the 8 dark colors. Every blinking
+// cell of a Canvas has such a background.
+func darkBlink(cell Cell) Cell {
+	if cell.Attr&AttrBlink != 0 && cell.BgRGB.A == 0 && cell.Bg > 7 && cell.Bg < 16 {
+		cell.Bg -= 8
+	}
+	return cell
+}
+
+// Canvas is a text mode screen: a grid of cells along with the font and
+// palette needed to draw them. Every decoder produces a Canvas, Draw turns it
+// into pixels.
+type Canvas struct {
+	Width       int
+	Height      int
+	Cells       []Cell // Width*Height cells, row by row
+	Font        *Font
+	Palette     Palette
+	Bits        int  // character cell width in pixels, 8 or 9
+	Transparent bool // draw palette background 0 as transparent
+}
+
+// maxCells bounds the size of a decoded canvas, so that a damaged header
+// can't make a decoder allocate gigabytes of cells
+const maxCells = 1 << 22
+
+// maxPixels bounds the size of a rendered image, which a canvas within
+// maxCells can still exceed with a large font or scale
+const maxPixels = 1 << 27
+
+// canvasFits reports whether a canvas of width by height cells stays within
+// maxCells
+func canvasFits(width, height int) bool {
+	return width >= 0 && height >= 0 && (width == 0 || height <= maxCells/width)
+}
+
+// NewCanvas returns a canvas of the given size cleared to blank cells, drawn
+// with the given font and palette and 8 pixel wide character cells
+func NewCanvas(width, height int, font *Font, palette Palette) *Canvas {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+
+	c := &Canvas{
+		Width:   width,
+		Height:  height,
+		Cells:   make([]Cell, width*height),
+		Font:    font,
+		Palette: palette,
+		Bits:    8,
+	}
+	c.Fill(blankCell)
+
+	return c
+}
+
+// Fill sets every cell of the canvas to cell
+func (c *Canvas) Fill(cell Cell) {
+	for i := range c.Cells {
+		c.Cells[i] = cell
+	}
+}
+
+// At returns the cell at column x of row y, or a blank cell outside the canvas
+func (c *Canvas) At(x, y int) Cell {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return blankCell
+	}
+	return c.Cells[y*c.Width+x]
+}
+
+// Set replaces the cell at column x of row y, positions outside the canvas are ignored
+func (c *Canvas) Set(x, y int, cell Cell) {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return
+	}
+	c.Cells[y*c.Width+x] = cell
+}
+
+// CellColors resolves the foreground and background colors of a cell,
+// taking the reverse, faint and conceal attributes into account
+func (c *Canvas) CellColors(cell Cell) (fg, bg color.RGBA) {
+	fgIndex, bgIndex := cell.Fg, cell.Bg
+	fgRGB, bgRGB := cell.FgRGB, cell.BgRGB
+
+	if cell.Attr&AttrReverse != 0 {
+		fgIndex, bgIndex = bgIndex, fgIndex
+		fgRGB, bgRGB = bgRGB, fgRGB
+	}
+
+	if fgRGB.A > 0 {
+		fg = fgRGB
+	} else {
+		fg = c.paletteColor(fgIndex)
+	}
+
+	if bgRGB.A > 0 {
+		bg = bgRGB
+	} else if c.Transparent && bgIndex == 0 {
+		bg = color.RGBA{}
+	} else {
+		bg = c.paletteColor(bgIndex)
+	}
+
+	// faint is halfway between the foreground and the background
+	if cell.Attr&AttrFaint != 0 {
+		fg = color.RGBA{
+			uint8((int(fg.R) + int(bg.R)) / 2),
+			uint8((int(fg.G) + int(bg.G)) / 2),
+			uint8((int(fg.B) + int(bg.B)) / 2),
+			fg.A,
+		}
+	}
+
+	if cell.Attr&AttrConceal != 0 {
+		fg = bg
+	}
+
+	return fg, bg
+}
+
+// textAttribute returns the attribute byte of a cell for a file with the 16
+// colors of p, other colors become the nearest of them. With blink the high
+// bit is the blink bit, leaving the dark backgrounds.
+func (c *Canvas) textAttribute(cell Cell, p Palette, blink bool) byte {
+	fg, bg := c.textColors(cell, p)
+
+	if blink {
+		bg &= 7
+		if cell.Attr&AttrBlink != 0 {
+			bg |= 8
+		}
+	}
+
+	return byte(bg<<4 | fg)
+}
+
+// textColors returns the colors of a cell among the 16 colors of p
+func (c *Canvas) textColors(cell Cell, p Palette) (fg, bg int) {
+	if cell.FgRGB.A == 0 && cell.BgRGB.A == 0 && cell.Fg < 16 && cell.Bg < 16 &&
+		cell.Attr&(AttrReverse|AttrFaint|AttrConceal) == 0 &&
+		c.paletteColor(cell.Fg) == p[cell.Fg] && c.paletteColor(cell.Bg) == p[cell.Bg] {
+		return cell.Fg, cell.Bg
+	}
+
+	fgRGB, bgRGB := c.CellColors(cell)
+	return p.nearest(fgRGB, 0, 16), p.nearest(bgRGB, 0, 16)
+}
+
+// blinks tells whether any cell has blinking text
+func (c *Canvas) blinks() bool {
+	for _, cell := range c.Cells {
+		if cell.Attr&AttrBlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// paletteColor looks up a palette entry, indexes outside the palette are black
+func (c *Canvas) paletteColor(index int) color.RGBA {
+	if index < 0 || index >= len(c.Palette) {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	return c.Palette[index]
+}
+
+// Draw renders the canvas to a new image, Bits pixels per column and the
+// font height per row. It returns ctx.Err() if ctx is canceled meanwhile.
+func (c *Canvas) Draw(ctx context.Context) (*image.RGBA, error) {
+	bits := c.Bits
+	if bits == 0 {
+		bits = 8
+	}
+
+	im := image.NewRGBA(image.Rect(0, 0, c.Width*bits, c.Height*c.Font.Height))
+
+	for y := 0; y < c.Height; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for x := 0; x < c.Width; x++ {
+			cell := c.Cells[y*c.Width+x]
+			fg, bg := c.CellColors(cell)
+
+			alDrawChar(im, c.Font.Data, bits, c.Font.Height, x, y, bg, fg, cell.Char, cell.Attr)
+		}
+	}
+
+	return im, nil
+}
@@ -0,0 +1,123 @@
+//  cat.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	goansi "github.com/ActiveState/go-ansi"
+)
+
+func catSynopsis() {
+	fmt.Fprint(os.Stderr, "\nSYNOPSIS:\n"+
+		"  go-ansi cat [options] file...\n\n"+
+		"OPTIONS:\n"+
+		"  -c columns  adjust number of columns for BIN files (default: 160)\n"+
+		"  -colors n   colors of the terminal: true, 256 or 16 (default: true if\n"+
+		"                $COLORTERM says so, 256 otherwise)\n"+
+		"  -f font     select font, which picks the code page (default: 80x25)\n"+
+		"  -i          enable iCE colors\n"+
+		"  -m mode     set rendering mode for ANS files: ced, transparent or\n"+
+		"                workbench\n"+
+		"  -t type     force file type instead of detecting it\n"+
+		"\n")
+}
+
+// cat prints files to a modern terminal, as UTF-8 text with color escapes,
+// and returns the exit status
+func cat(args []string) int {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	flags.Usage = catSynopsis
+
+	columns := flags.Int("c", 160, "-c columns")
+	colors := flags.String("colors", "", "-colors n")
+	fontName := flags.String("f", "80x25", "-f font")
+	icecolors := flags.Bool("i", false, "-i enable iCE colors")
+	mode := flags.String("m", "", "-m mode")
+	fileType := flags.String("t", "", "-t type")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitFailure
+	}
+
+	if *colors == "" {
+		*colors = "256"
+		if term := os.Getenv("COLORTERM"); term == "truecolor" || term == "24bit" {
+			*colors = "true"
+		}
+	}
+
+	var colorMode goansi.ColorMode
+	switch *colors {
+	case "true":
+		colorMode = goansi.ColorsTrue
+	case "256":
+		colorMode = goansi.Colors256
+	case "16":
+		colorMode = goansi.Colors16
+	default:
+		fmt.Fprint(os.Stderr, "\nInvalid value for colors.\n\n")
+		return ExitFailure
+	}
+
+	if !(*columns >= 1 && *columns <= 8192) {
+		fmt.Fprint(os.Stderr, "\nInvalid value for columns.\n\n")
+		return ExitFailure
+	}
+
+	format := goansi.FormatFromExt("." + *fileType)
+	if *fileType != "" && format == goansi.FormatAuto {
+		fmt.Fprint(os.Stderr, "\nInvalid value for type.\n\n")
+		return ExitFailure
+	}
+
+	if flags.NArg() == 0 {
+		catSynopsis()
+		return ExitFailure
+	}
+
+	status := ExitSuccess
+
+	for _, input := range flags.Args() {
+		err := catFile(input, colorMode, goansi.RenderOptions{
+			Format:    format,
+			FileName:  input,
+			Font:      *fontName,
+			Columns:   *columns,
+			Mode:      *mode,
+			IceColors: *icecolors,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", input, err)
+			status = ExitFailure
+		}
+	}
+
+	return status
+}
+
+// catFile decodes input and prints it
+func catFile(input string, colorMode goansi.ColorMode, opts goansi.RenderOptions) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+
+	result, err := goansi.Decode(context.Background(), f, opts)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	return result.Canvas.EncodeUTF8(os.Stdout, colorMode)
+}
@@ -15,12 +15,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"image"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+	"time"
 
 	goansi "github.com/ActiveState/go-ansi"
 )
@@ -43,7 +45,8 @@ const (
 func showHelp() {
 	fmt.Print("\nSUPPORTED FILE TYPES:\n" +
 		"  ANS  BIN  ADF  IDF  XB  PCB  TND  ASC  NFO  DIZ\n" +
-		"  Files with custom suffix default to the ANSi renderer.\n\n" +
+		"  The file type is detected from the content, the suffix is only used\n" +
+		"  as a hint. Unrecognized files default to the ANSi renderer.\n\n" +
 		"PC FONTS:\n" +
 		"  80x25              icelandic\n" +
 		"  80x50              latin1\n" +
@@ -71,9 +74,22 @@ func listExamples() {
 		"  go-ansi -r file.ans (adds Retina @2x output file)\n" +
 		"  go-ansi -o dir/file file.ans (custom path/name for output)\n" +
 		"  go-ansi -s file.bin (just display SAUCE record, don't generate output)\n" +
+		"  go-ansi -t bin file.dat (render as BIN instead of detecting the type)\n" +
 		"  go-ansi -m transparent file.ans (render with transparent background)\n" +
+		"  go-ansi -x gif -p 2400 -cursor file.ans (animate drawing at 2400 baud)\n" +
+		"  go-ansi -x apng file.bin (animate blinking text)\n" +
+		"  go-ansi -x html -sprite file.ans (selectable text drawn with the font)\n" +
+		"  go-ansi -x xb file.ans (convert to XBin with font and palette)\n" +
+		"  go-ansi -x ans -i -c 80 file.bin (convert to ANSi with iCE colors)\n" +
+		"  go-ansi -x idf file.adf (convert between editor formats)\n" +
+		"  go-ansi -b 9 -preview file.ans (check the output over SSH)\n" +
+		"  go-ansi -g sixel file.ans (show the image in a sixel terminal)\n" +
+		"  go-ansi cat -colors 256 file.xb (print to a terminal without truecolor)\n" +
+		"  go-ansi sauce set -title \"Title\" -author me file.ans (fix SAUCE fields)\n" +
+		"  go-ansi sauce -json dir (index the SAUCE records of a directory)\n" +
 		"  go-ansi -f amiga file.txt (custom font)\n" +
 		"  go-ansi -f 80x50 -b 9 -c 320 -i file.bin (custom font, bits, columns, icecolors)\n" +
+		"  go-ansi -n file.ans (ignore the rendering hints of the SAUCE record)\n" +
 		"\n")
 }
 
@@ -89,33 +105,121 @@ func versionInfo() {
 func synopsis() {
 	fmt.Print("\nSYNOPSIS:\n" +
 		"  go-ansi [options] file\n" +
+		"  go-ansi cat [options] file...\n" +
+		"  go-ansi sauce [-json] file|dir...\n" +
+		"  go-ansi sauce set [options] file...\n" +
 		"  go-ansi -e | -h | -v\n\n" +
 		"OPTIONS:\n" +
 		"  -b bits     set to 9 to render 9th column of block characters (default: 8)\n" +
-		"  -c columns  adjust number of columns for BIN files (default: 160)\n" +
+		"  -c columns  adjust number of columns for BIN files (default: 160),\n" +
+		"                and of the screen of -p (default: 80)\n" +
+		"  -d          stretch the image to the aspect ratio of a DOS screen\n" +
 		"  -e          print a list of examples\n" +
 		"  -f font     select font (default: 80x25)\n" +
+		"  -g protocol print the image to the terminal with a graphics protocol\n" +
+		"                instead of writing it: sixel or kitty\n" +
 		"  -h          show help\n" +
 		"  -i          enable iCE colors\n" +
-		"  -m mode     set rendering mode for ANS files:\n" +
+		"  -m mode     set rendering mode for ANS files and -p:\n" +
 		"                ced            black on gray, with 78 columns\n" +
 		"                transparent    render with transparent background\n" +
 		"                workbench      use Amiga Workbench palette\n" +
+		"  -n          ignore the SAUCE record, which otherwise sets the columns,\n" +
+		"                iCE colors, bits, aspect ratio and font that aren't\n" +
+		"                given as options\n" +
 		"  -o file     specify output filename/path\n" +
+		"  -p baud     animate an ANS file drawing at a modem speed, e.g. 2400\n" +
+		"                -fps rate      frames per second (default: 10)\n" +
+		"                -cursor        show the cursor\n" +
+		"                -hold seconds  keep the last frame (default: 3)\n" +
+		"  -preview    print the image to the terminal in 24-bit color, as wide\n" +
+		"                as $COLUMNS (default: 80), instead of writing it\n" +
 		"  -r          creates additional Retina @2x output file\n" +
 		"  -s          show SAUCE record without generating output\n" +
+		"  -t type     force file type instead of detecting it:\n" +
+		"                ans diz pcb bin adf idf tnd xb\n" +
 		"  -v          show version information\n" +
+		"  -x format   output format: png (default), gif, apng, html, svg,\n" +
+		"                ans, bin, adf, idf, tnd or xb, animated formats show\n" +
+		"                blinking text unless -p is given, ans needs 80\n" +
+		"                columns at most: -c 80 for BIN files\n" +
+		"                -sprite        draw html text with the font\n" +
+		"                -truecolor     write ans colors outside of the 16\n" +
+		"                               ANSi ones as 24-bit colors\n" +
 		"\n")
 }
 
-// Simple error checking to reduce duplicated code
+// playback plays input back at a modem speed
+func playback(input string, opts goansi.PlaybackOptions) (*goansi.Animation, error) {
+	in, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	return goansi.Playback(context.Background(), in, opts)
+}
+
+// writeAnimation writes the frames of anim to output as a GIF or an
+// animated PNG
+func writeAnimation(anim *goansi.Animation, output, format string) error {
+	if format == "gif" {
+		return writeFile(output, anim.EncodeGIF)
+	}
+	return writeFile(output, anim.EncodeAPNG)
+}
+
+// textFormats are the output formats of text mode files other than ANSi,
+// by their extensions
+var textFormats = map[string]func(*goansi.Canvas, io.Writer) error{
+	"adf": (*goansi.Canvas).EncodeADF,
+	"bin": (*goansi.Canvas).EncodeBin,
+	"idf": (*goansi.Canvas).EncodeIDF,
+	"tnd": (*goansi.Canvas).EncodeTundra,
+	"xb":  (*goansi.Canvas).EncodeXBin,
+}
+
+// writeFile creates output and writes it with encode
+func writeFile(output string, encode func(w io.Writer) error) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	if err := encode(out); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// terminalWidth returns the number of columns of the terminal, as exported
+// by the shell, or 80
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
+
+// check prints e and exits with a failure status if it isn't nil
 func check(e error) {
 	if e != nil {
-		panic(e)
+		fmt.Printf("\n%s\n\n", e)
+		os.Exit(ExitFailure)
 	}
 }
 
 func main() {
+	// subcommands print nothing but their output
+	if len(os.Args) > 1 && os.Args[1] == "cat" {
+		os.Exit(cat(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sauce" {
+		os.Exit(sauce(os.Args[2:]))
+	}
+
 	fmt.Printf("go-ansi %s - ANSi / ASCII art to PNG converter\n"+
 		"Copyright (C) 2017 ActiveState Software Inc. Written by Pete Garcin.\n", Version)
 
@@ -126,9 +230,23 @@ func main() {
 	// retina output bool type
 	createRetinaRep := false
 
+	// HTML output draws the text with the font as a CSS sprite
+	htmlSprite := false
+
+	// ANSi output keeps colors outside of the ANSi palette
+	trueColor := false
+
+	// print the image to the terminal instead of writing it
+	showPreview := false
+	var graphics string
+
 	// iCE colors bool type
 	icecolors := false
 
+	// DOS aspect ratio and the rendering hints of the SAUCE record
+	stretch := false
+	noHints := false
+
 	// analyze options and do what has to be done
 	fileIsBinary := false
 	fileIsANSi := false
@@ -137,6 +255,13 @@ func main() {
 
 	var mode string
 	var fontName string
+	var fileType string
+	var outputFormat string
+
+	// animation options
+	var baud, frameRate int
+	var showCursor bool
+	var holdSeconds float64
 
 	var input, output string
 	var retinaout string
@@ -150,19 +275,50 @@ func main() {
 	// Define command line flags for parsing
 	flag.IntVar(&bits, "b", 8, "-b bits")
 	flag.IntVar(&columns, "c", 160, "-c columns")
+	flag.BoolVar(&stretch, "d", false, "-d")
 	var exFl = flag.Bool("e", false, "-e show examples")
 	flag.StringVar(&fontName, "f", "80x25", "-f font")
+	flag.StringVar(&graphics, "g", "", "-g protocol")
 	var helpFl = flag.Bool("h", false, "-h show help")
 	flag.BoolVar(&icecolors, "i", false, "-i enable iCE colors")
 	flag.StringVar(&mode, "m", "", "-m mode")
+	flag.BoolVar(&noHints, "n", false, "-n")
 	flag.StringVar(&output, "o", "", "-o file")
+	flag.IntVar(&baud, "p", 0, "-p baud")
+	flag.BoolVar(&showPreview, "preview", false, "-preview")
+	flag.IntVar(&frameRate, "fps", 10, "-fps rate")
+	flag.BoolVar(&showCursor, "cursor", false, "-cursor")
+	flag.Float64Var(&holdSeconds, "hold", 3, "-hold seconds")
 	flag.BoolVar(&createRetinaRep, "r", false, "-r")
 	flag.BoolVar(&justDisplaySAUCE, "s", false, "-s")
+	flag.BoolVar(&htmlSprite, "sprite", false, "-sprite")
+	flag.StringVar(&fileType, "t", "", "-t type")
+	flag.BoolVar(&trueColor, "truecolor", false, "-truecolor")
 	var verFl = flag.Bool("v", false, "-v")
+	flag.StringVar(&outputFormat, "x", "png", "-x format")
 
 	// Parse command line args
 	flag.Parse()
 
+	// options given override the SAUCE record, even -i=false or -d=false
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	var skipHints goansi.SauceHint
+	for name, hint := range map[string]goansi.SauceHint{
+		"c": goansi.HintColumns,
+		"i": goansi.HintIceColors,
+		"b": goansi.HintBits,
+		"d": goansi.HintAspect,
+		"f": goansi.HintFont,
+	} {
+		if given[name] {
+			skipHints |= hint
+		}
+	}
+
 	// Error checking on values
 	if !(bits == 8 || bits == 9) {
 		fmt.Print("\nInvalid value for bits.\n\n")
@@ -174,6 +330,52 @@ func main() {
 		os.Exit(ExitFailure)
 	}
 
+	if outputFormat != "png" && outputFormat != "gif" && outputFormat != "apng" && outputFormat != "html" && outputFormat != "svg" && outputFormat != "ans" && textFormats[outputFormat] == nil {
+		fmt.Print("\nInvalid value for output format.\n\n")
+		os.Exit(ExitFailure)
+	}
+
+	animate := outputFormat == "gif" || outputFormat == "apng"
+
+	if baud < 0 || (baud > 0 && !animate) {
+		fmt.Print("\nInvalid value for baud, animations need -x gif or -x apng.\n\n")
+		os.Exit(ExitFailure)
+	}
+
+	// without a baud rate the animation is the blinking text, which iCE
+	// colors turn into bright backgrounds
+	if animate && baud == 0 && icecolors {
+		fmt.Print("\nBlink animation needs iCE colors off, use -p for playback.\n\n")
+		os.Exit(ExitFailure)
+	}
+
+	if graphics != "" && graphics != "sixel" && graphics != "kitty" {
+		fmt.Print("\nInvalid value for graphics protocol.\n\n")
+		os.Exit(ExitFailure)
+	}
+
+	// the image is printed instead of writing the output file
+	if graphics != "" {
+		showPreview = true
+	}
+
+	if showPreview && (outputFormat != "png" || createRetinaRep) {
+		fmt.Print("\nPreview shows the PNG output, it can't be combined with -r or -x.\n\n")
+		os.Exit(ExitFailure)
+	}
+
+	if frameRate < 1 || frameRate > 100 || holdSeconds < 0 {
+		fmt.Print("\nInvalid value for frame rate or hold time.\n\n")
+		os.Exit(ExitFailure)
+	}
+
+	// an empty type leaves the format to detection
+	format := goansi.FormatFromExt("." + fileType)
+	if fileType != "" && format == goansi.FormatAuto {
+		fmt.Print("\nInvalid value for type.\n\n")
+		os.Exit(ExitFailure)
+	}
+
 	if *exFl {
 		listExamples()
 		os.Exit(ExitSuccess)
@@ -197,10 +399,14 @@ func main() {
 	}
 
 	// let's check the file for a valid SAUCE record
-	record := goansi.GetSauce(input)
+	record, err := readSauce(input)
+	if err != nil {
+		fmt.Printf("\n%s\n\n", err)
+		os.Exit(ExitFailure)
+	}
 
 	// if we find a SAUCE record, update bool flag
-	if string(record.Sauceinf.ID[:]) == goansi.SauceID {
+	if record != nil {
 		fileHasSAUCE = true
 	}
 
@@ -214,71 +420,218 @@ func main() {
 			outputName = output
 		}
 
-		// appending ".png" extension to output file name
-		outputFile = outputName + ".png"
+		// appending the extension of the output format to output file name
+		outputFile = outputName + "." + outputFormat
 
-		if createRetinaRep {
+		if createRetinaRep && outputFormat == "png" {
 			retinaout = outputName + "@2x.png"
 		}
 
 		// display name of input and output files
 		fmt.Printf("\nInput File: %s\n", input)
-		fmt.Printf("Output File: %s\n", outputFile)
+		if !showPreview {
+			fmt.Printf("Output File: %s\n", outputFile)
+		}
 
-		if createRetinaRep {
+		if createRetinaRep && outputFormat == "png" {
 			fmt.Printf("Retina Output File: %s\n", retinaout)
 		}
 
-		// get file extension
-		fext := strings.ToLower(filepath.Ext(input))
-
-		// Open File
-		f, err := os.Open(input)
-		check(err)
-		// Get file size
-		fi, err := f.Stat()
-		check(err)
-		inputFileSize := fi.Size()
-		// Read File
-		inputFileBuffer := make([]byte, inputFileSize)
-		_, err = f.Read(inputFileBuffer)
-		check(err)
-		// close input file, we don't need it anymore
-		f.Close()
-
-		var outputImg image.Image
-		// create the output file by invoking the appropiate function
-		if fext == ".pcb" {
-			fileIsPCBoard = true
-		} else if fext == ".bin" {
-			fileIsBinary = true
-		} else if fext == ".tnd" {
-			fileIsTundra = true
+		if animate && baud > 0 {
+			opts := goansi.PlaybackOptions{
+				TerminalOptions: goansi.TerminalOptions{
+					Font:      fontName,
+					Bits:      bits,
+					Mode:      mode,
+					IceColors: icecolors,
+				},
+				Format:        format,
+				FileName:      input,
+				Baud:          baud,
+				FrameRate:     frameRate,
+				Cursor:        showCursor,
+				Hold:          time.Duration(holdSeconds * float64(time.Second)),
+				UseSauceHints: !noHints,
+				SkipHints:     skipHints,
+			}
+
+			// the screen is as wide as the record says unless -c is given
+			if given["c"] {
+				opts.Width = columns
+			}
+
+			// the hints only fill in the options that aren't given
+			if !noHints {
+				if !given["f"] {
+					opts.Font = ""
+				}
+				if !given["b"] {
+					opts.Bits = 0
+				}
+			}
+
+			anim, err := playback(input, opts)
+			if err == nil {
+				err = writeAnimation(anim, outputFile, outputFormat)
+			}
+			if err != nil {
+				fmt.Printf("\n%s\n\n", err)
+				os.Exit(ExitFailure)
+			}
+
+			fmt.Printf("Baud: %d\n", baud)
 		} else {
-			fileIsANSi = true
-		}
+			// Open File
+			f, err := os.Open(input)
+			check(err)
+
+			opts := goansi.RenderOptions{
+				Format:        format,
+				FileName:      input,
+				Font:          fontName,
+				Bits:          bits,
+				Columns:       columns,
+				Mode:          mode,
+				IceColors:     icecolors,
+				Stretch:       stretch,
+				UseSauceHints: !noHints,
+				SkipHints:     skipHints,
+			}
+
+			// the hints only fill in the options that aren't given
+			if !noHints {
+				if !given["f"] {
+					opts.Font = ""
+				}
+				if !given["b"] {
+					opts.Bits = 0
+				}
+				if !given["c"] {
+					opts.Columns = 0
+				}
+			}
 
-		// CLI does image resizing inside the pngw pkg to avoid parsing the file twice
-		outputImg = goansi.Parse(inputFileBuffer, inputFileSize, fontName, bits, columns, mode, icecolors, fext, 1.0)
+			// CLI does image resizing inside the pngw pkg to avoid parsing the file twice,
+			// the blink animation, HTML and SVG don't need the image
+			var result *goansi.Result
+			if outputFormat != "png" {
+				result, err = goansi.Decode(context.Background(), f, opts)
+			} else {
+				result, err = goansi.Render(context.Background(), f, opts)
+			}
+			// close input file, we don't need it anymore
+			f.Close()
+			if err != nil {
+				fmt.Printf("\n%s\n\n", err)
+				os.Exit(ExitFailure)
+			}
 
-		if outputImg != nil {
-			goansi.WritePng(outputFile, outputImg, 1.0)
-			if createRetinaRep {
-				goansi.WritePng(retinaout, outputImg, 2.0)
+			// the options as the SAUCE record left them
+			if result.Hints&goansi.HintIceColors != 0 {
+				icecolors = true
+			}
+			fontName = result.Canvas.Font.Name
+			bits = result.Canvas.Bits
+			if result.Hints&goansi.HintColumns != 0 {
+				columns = result.Canvas.Width
 			}
-		}
 
-		// gather information and report to the command line
-		if fileIsANSi || fileIsBinary ||
-			fileIsPCBoard || fileIsTundra {
-			fmt.Printf("Font: %s\n", fontName)
-			fmt.Printf("Bits: %d\n", bits)
-		}
-		if icecolors && (fileIsANSi || fileIsBinary) {
-			fmt.Printf("iCE Colors: enabled\n")
-		}
-		if fileIsBinary {
-			fmt.Printf("Columns: %d\n", columns)
+			// remember the detected file type for the report below
+			if result.Format == goansi.FormatPCBoard {
+				fileIsPCBoard = true
+			} else if result.Format == goansi.FormatBinary {
+				fileIsBinary = true
+			} else if result.Format == goansi.FormatTundra {
+				fileIsTundra = true
+			} else {
+				fileIsANSi = true
+			}
+
+			if animate {
+				anim, err := result.Canvas.BlinkAnimation(context.Background())
+				if err == nil {
+					err = writeAnimation(anim, outputFile, outputFormat)
+				}
+				if err != nil {
+					fmt.Printf("\n%s\n\n", err)
+					os.Exit(ExitFailure)
+				}
+			} else if outputFormat == "html" {
+				err := writeFile(outputFile, func(w io.Writer) error {
+					return result.Canvas.EncodeHTML(w, goansi.HTMLOptions{
+						Title:  filepath.Base(input),
+						Sprite: htmlSprite,
+					})
+				})
+				if err != nil {
+					fmt.Printf("\n%s\n\n", err)
+					os.Exit(ExitFailure)
+				}
+			} else if outputFormat == "svg" {
+				err := writeFile(outputFile, result.Canvas.EncodeSVG)
+				if err != nil {
+					fmt.Printf("\n%s\n\n", err)
+					os.Exit(ExitFailure)
+				}
+			} else if encode := textFormats[outputFormat]; encode != nil {
+				err := writeFile(outputFile, func(w io.Writer) error {
+					return encode(result.Canvas, w)
+				})
+				if err != nil {
+					fmt.Printf("\n%s\n\n", err)
+					os.Exit(ExitFailure)
+				}
+			} else if outputFormat == "ans" {
+				// BIN files are 160 columns wide unless -c says otherwise
+				if result.Canvas.Width > 80 {
+					fmt.Printf("\nANSi output has at most 80 columns, the file has %d: use -c 80.\n\n", result.Canvas.Width)
+					os.Exit(ExitFailure)
+				}
+
+				err := writeFile(outputFile, func(w io.Writer) error {
+					return result.Canvas.EncodeANSI(w, goansi.ANSIOptions{
+						IceColors: icecolors,
+						TrueColor: trueColor,
+					})
+				})
+				if err != nil {
+					fmt.Printf("\n%s\n\n", err)
+					os.Exit(ExitFailure)
+				}
+			} else if graphics == "sixel" {
+				fmt.Println()
+				check(goansi.EncodeSixel(os.Stdout, result.Image))
+				fmt.Println()
+			} else if graphics == "kitty" {
+				fmt.Println()
+				check(goansi.EncodeKitty(os.Stdout, result.Image))
+				fmt.Println()
+			} else if showPreview {
+				fmt.Println()
+				check(goansi.EncodePreview(os.Stdout, result.Image, terminalWidth(), goansi.ColorsTrue))
+			} else if result.Image != nil {
+				check(goansi.WritePng(outputFile, result.Image, 1.0))
+				if createRetinaRep {
+					check(goansi.WritePng(retinaout, result.Image, 2.0))
+				}
+			}
+
+			// gather information and report to the command line
+			fmt.Printf("Format: %s\n", result.Format)
+			if fileIsANSi || fileIsBinary ||
+				fileIsPCBoard || fileIsTundra {
+				fmt.Printf("Font: %s\n", fontName)
+				fmt.Printf("Bits: %d\n", bits)
+			}
+			if icecolors && (fileIsANSi || fileIsBinary) {
+				fmt.Printf("iCE Colors: enabled\n")
+			}
+			if fileIsBinary {
+				fmt.Printf("Columns: %d\n", columns)
+			}
+			if result.Hints != 0 {
+				fmt.Printf("SAUCE hints: %s\n", result.Hints)
+			}
 		}
 	}
 	// TODO SAUCE SUPPORT
@@ -286,36 +639,8 @@ func main() {
 	if !fileHasSAUCE {
 		fmt.Printf("\nFile %s does not have a SAUCE record.\n", input)
 	} else {
-		fmt.Printf("\nId: %s v%s\n", record.Sauceinf.ID, record.Sauceinf.Version)
-		fmt.Printf("Title: %s\n", record.Sauceinf.Title)
-		fmt.Printf("Author: %s\n", record.Sauceinf.Author)
-		fmt.Printf("Group: %s\n", record.Sauceinf.Group)
-		fmt.Printf("Date: %s\n", record.Sauceinf.Date)
-		fmt.Printf("Datatype: %d\n", record.Sauceinf.DataType)
-		fmt.Printf("Filetype: %d\n", record.Sauceinf.FileType)
-		if record.Sauceinf.Flags != 0 {
-			fmt.Printf("Flags: %d\n", record.Sauceinf.Flags)
-		}
-		if record.Sauceinf.Tinfo1 != 0 {
-			fmt.Printf("Tinfo1: %d\n", record.Sauceinf.Tinfo1)
-		}
-		if record.Sauceinf.Tinfo2 != 0 {
-			fmt.Printf("Tinfo2: %d\n", record.Sauceinf.Tinfo2)
-		}
-		if record.Sauceinf.Tinfo3 != 0 {
-			fmt.Printf("Tinfo3: %d\n", record.Sauceinf.Tinfo3)
-		}
-		if record.Sauceinf.Tinfo4 != 0 {
-			fmt.Printf("Tinfo4: %d\n", record.Sauceinf.Tinfo4)
-		}
-
-		fmt.Printf("Num comments: %d", record.Sauceinf.Comments)
-		if record.Sauceinf.Comments > 0 && len(record.CommentLines) > 0 {
-			fmt.Printf("Comments: ")
-			for i := 0; i < int(record.Sauceinf.Comments); i++ {
-				fmt.Printf("%s\n", record.CommentLines[i])
-			}
-		}
+		fmt.Println()
+		printSauce(os.Stdout, record)
 	}
 
 	os.Exit(ExitSuccess)
@@ -0,0 +1,424 @@
+//  sauce.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	goansi "github.com/ActiveState/go-ansi"
+)
+
+func sauceSynopsis() {
+	fmt.Fprint(os.Stderr, "\nSYNOPSIS:\n"+
+		"  go-ansi sauce [-json] file|dir...\n"+
+		"  go-ansi sauce set [options] file...\n\n"+
+		"Without set, the records of the files are shown, directories are\n"+
+		"searched for files.\n\n"+
+		"OPTIONS:\n"+
+		"  -json          print a JSON object per file, with the detected format,\n"+
+		"                   its size in characters and whether FileSize matches\n"+
+		"                   the size of the data\n\n"+
+		"SET OPTIONS:\n"+
+		"  -author name   set the author, up to 20 characters\n"+
+		"  -comment text  add a comment, the comments given replace the old\n"+
+		"                   ones, long ones take several lines of 64\n"+
+		"                   characters, an empty one removes them all\n"+
+		"  -group name    set the group, up to 20 characters\n"+
+		"  -title title   set the title, up to 35 characters\n"+
+		"\n"+
+		"Files without a SAUCE record get a new one dated today.\n"+
+		"\n")
+}
+
+// commentList collects the values of a repeated flag
+type commentList []string
+
+func (c *commentList) String() string {
+	return strings.Join(*c, "\n")
+}
+
+func (c *commentList) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+// sauce works with the SAUCE records of files and returns the exit status
+func sauce(args []string) int {
+	if len(args) > 0 && args[0] == "set" {
+		return sauceSet(args[1:])
+	}
+
+	return sauceShow(args)
+}
+
+// sauceShow prints the SAUCE records of files and of the files in
+// directories
+func sauceShow(args []string) int {
+	flags := flag.NewFlagSet("sauce", flag.ContinueOnError)
+	flags.Usage = sauceSynopsis
+
+	asJSON := flags.Bool("json", false, "-json")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitFailure
+	}
+
+	if flags.NArg() == 0 {
+		sauceSynopsis()
+		return ExitFailure
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+
+	status := ExitSuccess
+
+	show := func(path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = ExitFailure
+			return
+		}
+
+		if *asJSON {
+			if err := enc.Encode(sauceEntry(path, data)); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+				status = ExitFailure
+			}
+			return
+		}
+
+		record, err := goansi.ReadSauceBytes(data)
+		switch {
+		case errors.Is(err, goansi.ErrNoSauce):
+			fmt.Printf("%s: no SAUCE record\n\n", path)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = ExitFailure
+		default:
+			fmt.Printf("%s:\n", path)
+			printSauce(os.Stdout, record)
+			fmt.Println()
+		}
+	}
+
+	for _, input := range flags.Args() {
+		err := filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+				status = ExitFailure
+				return nil
+			}
+			if d.Type().IsRegular() {
+				show(path)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", input, err)
+			status = ExitFailure
+		}
+	}
+
+	return status
+}
+
+// printSauce prints the fields of a SAUCE record, decoded for its file type
+func printSauce(w io.Writer, record *goansi.Sauce) {
+	info := &record.Sauceinf
+
+	fmt.Fprintf(w, "Id: %s v%s\n", info.ID, info.Version)
+	fmt.Fprintf(w, "Title: %s\n", record.Title())
+	fmt.Fprintf(w, "Author: %s\n", record.Author())
+	fmt.Fprintf(w, "Group: %s\n", record.Group())
+	fmt.Fprintf(w, "Date: %s\n", sauceDate(info))
+	fmt.Fprintf(w, "Type: %s / %s\n", info.DataType, info.Type())
+	if flags, ok := info.ANSiFlags(); ok {
+		fmt.Fprintf(w, "Flags: %s\n", flags)
+		if font := info.FontName(); font != "" {
+			fmt.Fprintf(w, "Font: %s\n", font)
+		}
+	} else if info.Flags != 0 {
+		fmt.Fprintf(w, "Flags: %d\n", info.Flags)
+	}
+
+	// Tinfo fields by what they mean for the file type
+	names := info.TinfoNames()
+	for i, value := range sauceTinfo(info) {
+		if names[i] != "" {
+			fmt.Fprintf(w, "%s: %d\n", names[i], value)
+		} else if value != 0 {
+			fmt.Fprintf(w, "Tinfo%d: %d\n", i+1, value)
+		}
+	}
+	if info.Type() == goansi.FileBinaryText {
+		columns, _ := info.Size()
+		fmt.Fprintf(w, "Character width: %d\n", columns)
+	}
+
+	fmt.Fprintf(w, "Num comments: %d\n", info.Comments)
+	if len(record.CommentLines) > 0 {
+		fmt.Fprintf(w, "Comments: ")
+		for _, line := range record.CommentLines {
+			fmt.Fprintf(w, "%s\n", line)
+		}
+	}
+}
+
+// sauceDate returns the date of a record as YYYY-MM-DD, or as it is if it
+// isn't a date
+func sauceDate(info *goansi.SauceInfo) string {
+	if date, ok := info.Time(); ok {
+		return date.Format("2006-01-02")
+	}
+	return strings.TrimRight(string(info.Date[:]), " \x00")
+}
+
+// sauceTinfo returns the Tinfo fields of a record
+func sauceTinfo(info *goansi.SauceInfo) [4]uint16 {
+	return [4]uint16{info.Tinfo1, info.Tinfo2, info.Tinfo3, info.Tinfo4}
+}
+
+// sauceJSON is the JSON object sauce -json prints for a file
+type sauceJSON struct {
+	File    string       `json:"file"`
+	Format  string       `json:"format"`
+	Columns int          `json:"columns"`
+	Lines   int          `json:"lines"`
+	Error   string       `json:"error,omitempty"`
+	Sauce   *sauceRecord `json:"sauce"`
+}
+
+// sauceRecord holds the decoded fields of a SAUCE record
+type sauceRecord struct {
+	Version         string         `json:"version"`
+	Title           string         `json:"title"`
+	Author          string         `json:"author"`
+	Group           string         `json:"group"`
+	Date            string         `json:"date"`
+	DataType        string         `json:"dataType"`
+	FileType        string         `json:"fileType"`
+	FileSize        int32          `json:"fileSize"`
+	DataSize        int            `json:"dataSize"`
+	FileSizeMatches bool           `json:"fileSizeMatches"`
+	Tinfo           [4]uint16      `json:"tinfo"`
+	Info            map[string]int `json:"info,omitempty"`
+	Flags           byte           `json:"flags"`
+	ANSiFlags       *sauceFlags    `json:"ansiFlags,omitempty"`
+	Font            string         `json:"font,omitempty"`
+	Comments        []string       `json:"comments"`
+}
+
+// sauceFlags holds the decoded ANSiFlags of a SAUCE record
+type sauceFlags struct {
+	IceColors     bool   `json:"iceColors"`
+	LetterSpacing int    `json:"letterSpacing"`
+	AspectRatio   string `json:"aspectRatio"`
+}
+
+// sauceEntry returns the JSON object of a file. The file is decoded with
+// the hints of its record for the size in characters, files that fail to
+// decode keep the detected format and get the error.
+func sauceEntry(path string, data []byte) sauceJSON {
+	entry := sauceJSON{File: path}
+
+	record, err := goansi.ReadSauceBytes(data)
+	if err != nil && !errors.Is(err, goansi.ErrNoSauce) {
+		entry.Error = err.Error()
+		record = nil
+	}
+
+	result, err := goansi.Decode(context.Background(), bytes.NewReader(data), goansi.RenderOptions{
+		FileName:      path,
+		UseSauceHints: true,
+	})
+	if err == nil {
+		entry.Format = result.Format.String()
+		entry.Columns = result.Canvas.Width
+		entry.Lines = result.Canvas.Height
+	} else {
+		format, _ := goansi.Detect(data)
+		entry.Format = format.String()
+		if entry.Error == "" {
+			entry.Error = err.Error()
+		}
+	}
+
+	if record == nil {
+		return entry
+	}
+
+	info := &record.Sauceinf
+	dataSize := len(goansi.StripSauce(data, record))
+
+	entry.Sauce = &sauceRecord{
+		Version:         strings.TrimRight(string(info.Version[:]), " \x00"),
+		Title:           record.Title(),
+		Author:          record.Author(),
+		Group:           record.Group(),
+		Date:            sauceDate(info),
+		DataType:        info.DataType.String(),
+		FileType:        info.Type().String(),
+		FileSize:        info.FileSize,
+		DataSize:        dataSize,
+		FileSizeMatches: int(info.FileSize) == dataSize,
+		Tinfo:           sauceTinfo(info),
+		Flags:           info.Flags,
+		Font:            info.FontName(),
+		Comments:        record.CommentLines,
+	}
+
+	if entry.Sauce.Comments == nil {
+		entry.Sauce.Comments = []string{}
+	}
+
+	// Tinfo fields by what they mean for the file type
+	names := info.TinfoNames()
+	for i, value := range entry.Sauce.Tinfo {
+		if names[i] != "" {
+			if entry.Sauce.Info == nil {
+				entry.Sauce.Info = make(map[string]int)
+			}
+			entry.Sauce.Info[names[i]] = int(value)
+		}
+	}
+
+	if flags, ok := info.ANSiFlags(); ok {
+		entry.Sauce.ANSiFlags = &sauceFlags{
+			IceColors:     flags.IceColors(),
+			LetterSpacing: flags.LetterSpacing(),
+			AspectRatio:   flags.AspectRatio().String(),
+		}
+	}
+
+	return entry
+}
+
+// readSauce returns the SAUCE record of a file, nil if it has none
+func readSauce(path string) (*goansi.Sauce, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	record, err := goansi.ReadSauce(f)
+	if errors.Is(err, goansi.ErrNoSauce) {
+		return nil, nil
+	}
+	return record, err
+}
+
+// sauceSet changes fields of the SAUCE records of files
+func sauceSet(args []string) int {
+	flags := flag.NewFlagSet("sauce set", flag.ContinueOnError)
+	flags.Usage = sauceSynopsis
+
+	var comments commentList
+	author := flags.String("author", "", "-author name")
+	flags.Var(&comments, "comment", "-comment text")
+	group := flags.String("group", "", "-group name")
+	title := flags.String("title", "", "-title title")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitFailure
+	}
+
+	// only the fields given change, even to nothing
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for _, field := range []struct {
+		name  string
+		value string
+		size  int
+	}{
+		{"title", *title, 35},
+		{"author", *author, 20},
+		{"group", *group, 20},
+	} {
+		if utf8.RuneCountInString(field.value) > field.size {
+			fmt.Fprintf(os.Stderr, "\nInvalid value for %s, it has more than %d characters.\n\n", field.name, field.size)
+			return ExitFailure
+		}
+	}
+
+	var lines []string
+	for _, comment := range comments {
+		lines = append(lines, commentLines(comment)...)
+	}
+
+	if len(lines) > 255 {
+		fmt.Fprint(os.Stderr, "\nInvalid value for comment, there are more than 255 lines.\n\n")
+		return ExitFailure
+	}
+
+	if flags.NArg() == 0 || len(given) == 0 {
+		sauceSynopsis()
+		return ExitFailure
+	}
+
+	status := ExitSuccess
+
+	for _, input := range flags.Args() {
+		err := goansi.UpdateSauce(input, func(record *goansi.Sauce) {
+			if given["title"] {
+				record.SetTitle(*title)
+			}
+			if given["author"] {
+				record.SetAuthor(*author)
+			}
+			if given["group"] {
+				record.SetGroup(*group)
+			}
+			if given["comment"] {
+				record.CommentLines = lines
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", input, err)
+			status = ExitFailure
+		}
+	}
+
+	return status
+}
+
+// commentLines splits a comment into SAUCE comment lines of 64 characters,
+// an empty comment has none
+func commentLines(comment string) []string {
+	var lines []string
+
+	runes := []rune(comment)
+	for len(runes) > 0 {
+		n := len(runes)
+		if n > 64 {
+			n = 64
+		}
+		lines = append(lines, string(runes[:n]))
+		runes = runes[n:]
+	}
+
+	return lines
+}
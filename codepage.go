@@ -0,0 +1,167 @@
+//  codepage.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import "strings"
+
+// cp437 holds the Unicode characters of code page 437, 32 per line. Control
+// characters are the symbols a VGA font draws for them, NUL is a space.
+var cp437 = []rune(strings.Join([]string{
+	" ☺☻♥♦♣♠•◘○◙♂♀♪♫☼►◄↕‼¶§▬↨↑↓→←∟↔▲▼",
+	" !\"#$%&'()*+,-./0123456789:;<=>?",
+	"@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_",
+	"`abcdefghijklmnopqrstuvwxyz{|}~⌂",
+	"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒ",
+	"áíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+}, ""))
+
+// fontCodePages maps the names of the PC fonts to their code page
+var fontCodePages = map[string]int{
+	"80x25":           437,
+	"80x50":           437,
+	"terminus":        437,
+	"baltic":          775,
+	"cyrillic":        855,
+	"french-canadian": 863,
+	"greek":           737,
+	"greek-869":       869,
+	"hebrew":          862,
+	"icelandic":       861,
+	"latin1":          850,
+	"latin2":          852,
+	"nordic":          865,
+	"portuguese":      860,
+	"russian":         866,
+	"turkish":         857,
+}
+
+// codePages holds the upper half of the code pages other than 437, their
+// lower half is the same. Characters a code page leaves undefined are
+// taken from code page 437.
+var codePages = map[int][]rune{
+	737: codePage(
+		"ΑΒΓΔΕΖΗΘΙΚΛΜΝΞΟΠΡΣΤΥΦΧΨΩαβγδεζηθ",
+		"ικλμνξοπρσςτυφχψ░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"ωάέήϊίόύϋώΆΈΉΊΌΎΏ±≥≤ΪΫ÷≈°∙·√ⁿ²■\u00a0",
+	),
+	775: codePage(
+		"ĆüéāäģåćłēŖŗīŹÄÅÉæÆōöĢ¢ŚśÖÜø£Ø×¤",
+		"ĀĪóŻżź”¦©®¬½¼Ł«»░▒▓│┤ĄČĘĖ╣║╗╝ĮŠ┐",
+		"└┴┬├─┼ŲŪ╚╔╩╦╠═╬Žąčęėįšųūž┘┌█▄▌▐▀",
+		"ÓßŌŃõÕµńĶķĻļņĒŅ’\u00ad±“¾¶§÷„°∙·¹³²■\u00a0",
+	),
+	850: codePage(
+		"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒ",
+		"áíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐",
+		"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀",
+		"ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0",
+	),
+	852: codePage(
+		"ÇüéâäůćçłëŐőîŹÄĆÉĹĺôöĽľŚśÖÜŤťŁ×č",
+		"áíóúĄąŽžĘę¬źČş«»░▒▓│┤ÁÂĚŞ╣║╗╝Żż┐",
+		"└┴┬├─┼Ăă╚╔╩╦╠═╬¤đĐĎËďŇÍÎě┘┌█▄ŢŮ▀",
+		"ÓßÔŃńňŠšŔÚŕŰýÝţ´\u00ad˝˛ˇ˘§÷¸°¨˙űŘř■\u00a0",
+	),
+	855: codePage(
+		"ђЂѓЃёЁєЄѕЅіІїЇјЈљЉњЊћЋќЌўЎџЏюЮъЪ",
+		"аАбБцЦдДеЕфФгГ«»░▒▓│┤хХиИ╣║╗╝йЙ┐",
+		"└┴┬├─┼кК╚╔╩╦╠═╬¤лЛмМнНоОп┘┌█▄Пя▀",
+		"ЯрРсСтТуУжЖвВьЬ№\u00adыЫзЗшШэЭщЩчЧ§■\u00a0",
+	),
+	857: codePage(
+		"ÇüéâäàåçêëèïîıÄÅÉæÆôöòûùİÖÜø£ØŞş",
+		"áíóúñÑĞğ¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐",
+		"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ºªÊËÈ╒ÍÎÏ┘┌█▄¦Ì▀",
+		"ÓßÔÒõÕµτ×ÚÛÙìÿ¯´\u00ad±≥¾¶§÷¸°¨·¹³²■\u00a0",
+	),
+	860: codePage(
+		"ÇüéâãàÁçêÊèÍÔìÃÂÉÀÈôõòÚùÌÕÜ¢£Ù₧Ó",
+		"áíóúñÑªº¿Ò¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	),
+	861: codePage(
+		"ÇüéâäàåçêëèÐðÞÄÅÉæÆôöþûÝýÖÜø£Ø₧ƒ",
+		"áíóúÁÍÓÚ¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	),
+	862: codePage(
+		"אבגדהוזחטיךכלםמןנסעףפץצקרשת¢£¥₧ƒ",
+		"áíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	),
+	863: codePage(
+		"ÇüéâÂà¶çêëèïî‗À§ÉÈÊôËÏûù¤ÔÜ¢£ÙÛƒ",
+		"¦´óú¨¸³¯Î⌐¬½¼¾«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	),
+	865: codePage(
+		"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø₧ƒ",
+		"áíóúñÑªº¿⌐¬½¼¡«¤░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	),
+	866: codePage(
+		"АБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ",
+		"абвгдежзийклмноп░▒▓│┤╡╢╖╕╣║╗╝╜╛┐",
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀",
+		"рстуфхцчшщъыьэюяЁёЄєЇїЎў°∙·√№¤■\u00a0",
+	),
+	869: codePage(
+		"ÇüéâäàΆç·¬¦‘’Έ―ΉΊΪΌôöΎΫ©Ώ²³ά£έήί",
+		"ϊΐόύΑΒΓΔΕΖΗ½ΘΙ«»░▒▓│┤ΚΛΜΝ╣║╗╝ΞΟ┐",
+		"└┴┬├─┼ΠΡ╚╔╩╦╠═╬ΣΤΥΦΧΨΩαβγ┘┌█▄δε▀",
+		"ζηθικλμνξοπρσςτ΄\u00ad±υφχ§ψ΅°¨ωϋΰώ■\u00a0",
+	),
+}
+
+// codePage joins the lines of a code page table
+func codePage(lines ...string) []rune {
+	return []rune(strings.Join(lines, ""))
+}
+
+// toUnicode returns the Unicode character the font draws for a character,
+// from the code page of the font. Amiga fonts follow ISO 8859-1, without
+// glyphs for the control characters.
+func (f *Font) toUnicode(char byte) rune {
+	if f.Amiga {
+		if char < 32 || (char >= 127 && char < 160) {
+			return ' '
+		}
+		return rune(char)
+	}
+
+	if table, ok := codePages[f.CodePage]; ok && char >= 128 {
+		return table[char-128]
+	}
+
+	return cp437[char]
+}
+
+// cp437Byte returns the code page 437 character of r, or '?' if it has none
+func cp437Byte(r rune) byte {
+	if r < 0x80 {
+		return byte(r)
+	}
+
+	for i := 0x80; i < len(cp437); i++ {
+		if cp437[i] == r {
+			return byte(i)
+		}
+	}
+
+	return '?'
+}
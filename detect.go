@@ -0,0 +1,181 @@
+//  detect.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+)
+
+// Confidence reports how certain Detect is about a format
+type Confidence int
+
+// Confidence levels, in increasing order
+const (
+	ConfidenceNone   Confidence = iota // nothing matched, the format is a fallback
+	ConfidenceLow                      // weak hint such as the data layout
+	ConfidenceMedium                   // content heuristics matched
+	ConfidenceHigh                     // magic bytes or SAUCE record matched
+)
+
+var confidenceNames = [...]string{
+	ConfidenceNone:   "none",
+	ConfidenceLow:    "low",
+	ConfidenceMedium: "medium",
+	ConfidenceHigh:   "high",
+}
+
+func (c Confidence) String() string {
+	if c < 0 || int(c) >= len(confidenceNames) {
+		return "unknown"
+	}
+	return confidenceNames[c]
+}
+
+// signatures at the start of the formats that have one
+var (
+	idfSignature    = []byte("\x041.4")
+	idfOldSignature = []byte("\x041.3")
+)
+
+// adfHeaderSize is the version byte, 64 color palette and font of an ADF file
+const adfHeaderSize = 1 + 192 + 4096
+
+// Detect guesses the format of a complete file from its content. It looks at
+// magic bytes first, then at the SAUCE record and finally at heuristics over
+// the data. Data that matches nothing is reported as ANSi with ConfidenceNone.
+func Detect(data []byte) (Format, Confidence) {
+	// a damaged record is no worse than a missing one here
+	record, _ := readRecord(bytes.NewReader(data))
+
+	return detect(StripSauce(data, record), record)
+}
+
+// detect does the work for Detect on the data preceding the SAUCE record
+func detect(data []byte, record *Sauce) (Format, Confidence) {
+	// magic bytes
+	switch {
+	case bytes.HasPrefix(data, []byte(xbinID)):
+		return FormatXBin, ConfidenceHigh
+	case len(data) > len(tundraHeader) && data[0] == 24 && string(data[1:1+len(tundraHeader)]) == tundraHeader:
+		return FormatTundra, ConfidenceHigh
+	case bytes.HasPrefix(data, idfSignature) || bytes.HasPrefix(data, idfOldSignature):
+		return FormatIceDraw, ConfidenceHigh
+	}
+
+	// SAUCE DataType and FileType
+	if record != nil && string(record.Sauceinf.ID[:]) == SauceID {
+		switch record.Sauceinf.Type() {
+		case FileASCII, FileANSi, FileANSiMation:
+			return FormatANSI, ConfidenceHigh
+		case FilePCBoard:
+			return FormatPCBoard, ConfidenceHigh
+		case FileTundraDraw:
+			return FormatTundra, ConfidenceHigh
+		case FileBinaryText:
+			return FormatBinary, ConfidenceHigh
+		case FileXBin:
+			return FormatXBin, ConfidenceHigh
+		}
+	}
+
+	// heuristics
+	if isArtworx(data) {
+		return FormatArtworx, ConfidenceMedium
+	}
+
+	escapes := bytes.Count(data, []byte("\x1b["))
+	pcbCodes := countPCBCodes(data)
+
+	// a few @X codes per kilobyte without competing escape sequences
+	if pcbCodes > escapes && pcbCodes*1024 >= len(data) {
+		return FormatPCBoard, ConfidenceMedium
+	}
+
+	if escapes > 0 {
+		return FormatANSI, ConfidenceMedium
+	}
+
+	if isBinary(data) {
+		return FormatBinary, ConfidenceLow
+	}
+
+	return FormatANSI, ConfidenceNone
+}
+
+// detectFormat picks the format Render decodes data as. Confident detection
+// wins over the extension, which wins over weaker guesses.
+func detectFormat(data []byte, record *Sauce, ext string) Format {
+	format, confidence := detect(data, record)
+	extFormat := FormatFromExt(ext)
+
+	// DIZ files are ANSi that only the file name can tell apart
+	if format == FormatANSI && extFormat == FormatDIZ {
+		return FormatDIZ
+	}
+
+	if confidence < ConfidenceHigh && extFormat != FormatAuto {
+		return extFormat
+	}
+
+	return format
+}
+
+// isArtworx checks for the ADF version byte followed by a 6-bit VGA palette
+func isArtworx(data []byte) bool {
+	if len(data) < adfHeaderSize || data[0] != 1 {
+		return false
+	}
+
+	for _, c := range data[1:193] {
+		if c > 63 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// countPCBCodes counts the @X color codes in data
+func countPCBCodes(data []byte) int {
+	count := 0
+
+	for i := 0; i+3 < len(data); i++ {
+		if data[i] != '@' || data[i+1] != 'X' {
+			continue
+		}
+
+		_, bgOk := pcbHexDigit(data[i+2])
+		_, fgOk := pcbHexDigit(data[i+3])
+
+		if bgOk && fgOk {
+			count++
+			i += 3
+		}
+	}
+
+	return count
+}
+
+// isBinary checks for character/attribute pairs: an even number of whole
+// 80 column rows with plenty of control bytes that text files don't contain
+func isBinary(data []byte) bool {
+	if len(data) == 0 || len(data)%160 != 0 {
+		return false
+	}
+
+	controls := 0
+	for _, c := range data {
+		if c < 32 && c != 9 && c != 10 && c != 13 && c != 26 {
+			controls++
+		}
+	}
+
+	return controls*10 >= len(data)
+}
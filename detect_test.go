@@ -0,0 +1,91 @@
+//  detect_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// withSauce returns data followed by a SAUCE record of file type f
+func withSauce(t *testing.T, data string, f FileType) []byte {
+	t.Helper()
+
+	var record Sauce
+	record.Sauceinf.SetType(f)
+
+	buf := bytes.NewBufferString(data)
+	if err := WriteSauce(buf, record); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	adf := append([]byte{1}, make([]byte, adfHeaderSize)...)
+	bin := bytes.Repeat([]byte{0xDB, 0x1E}, 80)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		format     Format
+		confidence Confidence
+	}{
+		{"xbin", []byte(xbinID + "\x50\x00\x19\x00\x10\x00"), FormatXBin, ConfidenceHigh},
+		{"tundra", []byte("\x18" + tundraHeader + "data"), FormatTundra, ConfidenceHigh},
+		{"icedraw", []byte("\x041.4\x00\x00\x00\x00\x4f\x00\x18\x00"), FormatIceDraw, ConfidenceHigh},
+		{"old icedraw", []byte("\x041.3\x00\x00\x00\x00\x4f\x00\x18\x00"), FormatIceDraw, ConfidenceHigh},
+		{"sauce ansi", withSauce(t, "text", FileANSi), FormatANSI, ConfidenceHigh},
+		{"sauce pcboard", withSauce(t, "text", FilePCBoard), FormatPCBoard, ConfidenceHigh},
+		{"sauce tundra", withSauce(t, "text", FileTundraDraw), FormatTundra, ConfidenceHigh},
+		{"sauce binary", withSauce(t, "text", FileBinaryText), FormatBinary, ConfidenceHigh},
+		{"sauce xbin", withSauce(t, "text", FileXBin), FormatXBin, ConfidenceHigh},
+		{"magic bytes win over sauce", withSauce(t, xbinID, FileANSi), FormatXBin, ConfidenceHigh},
+		{"sauce of no text format", withSauce(t, "\x1b[1mtext", FileGIF), FormatANSI, ConfidenceMedium},
+		{"artworx", adf, FormatArtworx, ConfidenceMedium},
+		{"pcboard", []byte("@X0Fhello @X1Eworld"), FormatPCBoard, ConfidenceMedium},
+		{"ansi", []byte("\x1b[1;31mhello"), FormatANSI, ConfidenceMedium},
+		{"binary", bin, FormatBinary, ConfidenceLow},
+		{"binary of part of a row", bin[:80], FormatANSI, ConfidenceNone},
+		{"text", []byte("hello\r\n"), FormatANSI, ConfidenceNone},
+		{"empty", nil, FormatANSI, ConfidenceNone},
+		{"long text", []byte(strings.Repeat("x", 1000)), FormatANSI, ConfidenceNone},
+	}
+
+	for _, tt := range tests {
+		format, confidence := Detect(tt.data)
+		if format != tt.format || confidence != tt.confidence {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, format, confidence, tt.format, tt.confidence)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		data   string
+		ext    string
+		format Format
+	}{
+		{"hello", ".diz", FormatDIZ},
+		{"\x1b[1mhello", ".DIZ", FormatDIZ},
+		{"hello", ".bin", FormatBinary},
+		{"hello", ".txt", FormatANSI},
+		{"hello", "", FormatANSI},
+		// confident detection wins over the extension
+		{xbinID, ".ans", FormatXBin},
+	}
+
+	for _, tt := range tests {
+		if format := detectFormat([]byte(tt.data), nil, tt.ext); format != tt.format {
+			t.Errorf("%q %q: got %v, want %v", tt.data, tt.ext, format, tt.format)
+		}
+	}
+}
@@ -22,20 +22,48 @@ import (
 )
 
 // AlDrawChar - shared method for drawing ANSI characters into an image buffer
-func alDrawChar(im draw.Image, font []byte, bits int, fontSizeY int, positionX int, positionY int, colorBackground color.RGBA, colorForeground color.RGBA, character byte) {
+func alDrawChar(im draw.Image, font []byte, bits int, fontSizeY int, positionX int, positionY int, colorBackground color.RGBA, colorForeground color.RGBA, character byte, attr Attr) {
 	x := positionX * bits
 	y := positionY * fontSizeY
 
 	draw.Draw(im, image.Rect(x, y, x+bits, y+fontSizeY), &image.Uniform{colorBackground}, image.ZP, draw.Src)
 
-	for line := 0; line < fontSizeY; line++ {
-		for column := 0; column < bits; column++ {
-			if (font[line+int(character)*fontSizeY] & (0x80 >> uint(column))) != 0 {
-				im.Set(positionX*bits+column, positionY*fontSizeY+line, colorForeground)
-				if bits == 9 && column == 7 && character > 191 && character < 224 {
-					im.Set(positionX*bits+8, positionY*fontSizeY+line, colorForeground)
+	// glyphs missing from a short font are left blank
+	if (int(character)+1)*fontSizeY <= len(font) {
+		for line := 0; line < fontSizeY; line++ {
+			row := font[line+int(character)*fontSizeY]
+
+			// italics shear the glyph to the right, more towards the top,
+			// but never past the right edge of the cell
+			shift := 0
+			if attr&AttrItalic != 0 && row != 0 {
+				right := 7
+				for row&(0x80>>uint(right)) == 0 {
+					right--
+				}
+				if right == 7 && bits == 9 && character > 191 && character < 224 {
+					right = 8
+				}
+				shift = min((fontSizeY-1-line)/4, bits-1-right)
+			}
+
+			for column := 0; column < bits; column++ {
+				if (row & (0x80 >> uint(column))) != 0 {
+					im.Set(x+column+shift, y+line, colorForeground)
+					if bits == 9 && column == 7 && shift == 0 && character > 191 && character < 224 {
+						im.Set(x+8, y+line, colorForeground)
+					}
 				}
 			}
 		}
 	}
+
+	// underline on the bottom row, strikethrough across the middle
+	if attr&AttrUnderline != 0 {
+		draw.Draw(im, image.Rect(x, y+fontSizeY-1, x+bits, y+fontSizeY), &image.Uniform{colorForeground}, image.ZP, draw.Src)
+	}
+
+	if attr&AttrStrike != 0 {
+		draw.Draw(im, image.Rect(x, y+fontSizeY/2, x+bits, y+fontSizeY/2+1), &image.Uniform{colorForeground}, image.ZP, draw.Src)
+	}
 }
@@ -0,0 +1,73 @@
+//  drawchar_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// drawGlyph draws char on a black cell and returns which pixels are white
+func drawGlyph(char byte, bits int, attr Attr) [][]bool {
+	font := SelectFont("80x25")
+	white := color.RGBA{255, 255, 255, 255}
+
+	im := image.NewRGBA(image.Rect(0, 0, bits, font.Height))
+	alDrawChar(im, font.Data, bits, font.Height, 0, 0, color.RGBA{0, 0, 0, 255}, white, char, attr)
+
+	pixels := make([][]bool, font.Height)
+	for y := range pixels {
+		pixels[y] = make([]bool, bits)
+		for x := range pixels[y] {
+			pixels[y][x] = im.RGBAAt(x, y) == white
+		}
+	}
+	return pixels
+}
+
+func TestItalicGlyphs(t *testing.T) {
+	for _, bits := range []int{8, 9} {
+		for _, char := range []byte{'A', 0xDB, 0xC4, 0xB3} {
+			plain, italic := drawGlyph(char, bits, 0), drawGlyph(char, bits, AttrItalic)
+
+			// every row keeps its pixels, shifted right as a whole
+			for y := range plain {
+				shift := -1
+				for s := 0; s < bits && shift < 0; s++ {
+					same := true
+					for x := range plain[y] {
+						moved := x-s >= 0 && plain[y][x-s]
+						if italic[y][x] != moved || (x >= bits-s && plain[y][x]) {
+							same = false
+							break
+						}
+					}
+					if same {
+						shift = s
+					}
+				}
+				if shift < 0 {
+					t.Errorf("%d bits, %#x: row %d is %v, not %v shifted right", bits, char, y, italic[y], plain[y])
+				}
+			}
+		}
+	}
+
+	// a glyph with room to the right leans over at the top
+	plain, italic := drawGlyph('|', 8, 0), drawGlyph('|', 8, AttrItalic)
+	top := 1
+	for !plain[top][3] {
+		top++
+	}
+	if italic[top][3] || !italic[top][3+(16-1-top)/4] {
+		t.Errorf("'|' row %d is %v, want it shifted by %d", top, italic[top], (16-1-top)/4)
+	}
+}
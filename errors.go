@@ -0,0 +1,76 @@
+//  errors.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors reported by the format decoders and the options they are given.
+// They are always wrapped in a *FormatError or an *OpError, use errors.Is to
+// test for them.
+var (
+	// ErrNotXBin is returned when XBin data lacks the "XBIN\x1a" signature
+	ErrNotXBin = errors.New("not an XBin file")
+	// ErrNotTundra is returned when Tundra data lacks the "TUNDRA24" header
+	ErrNotTundra = errors.New("not a Tundra file")
+	// ErrNotANSI is returned by Playback for files of other formats
+	ErrNotANSI = errors.New("not an ANSi file")
+	// ErrBadHeader is returned when a header or SAUCE field is out of range
+	ErrBadHeader = errors.New("bad header")
+	// ErrTruncated is returned when the data ends in the middle of a structure
+	ErrTruncated = errors.New("unexpected end of data")
+	// ErrBadOption is returned when a rendering option can't be used
+	ErrBadOption = errors.New("invalid option")
+	// ErrTooLarge is returned when an image would take more than maxPixels
+	ErrTooLarge = errors.New("image too large")
+)
+
+// ErrNoSauce is returned by ReadSauce when the data doesn't end with a SAUCE
+// record, it is returned as it is
+var ErrNoSauce = errors.New("no SAUCE record")
+
+// FormatError reports a problem found while decoding a file
+type FormatError struct {
+	Format string // format being decoded, e.g. "XBin", or "SAUCE" for the record
+	Offset int64  // byte offset in the input where the problem was found
+	Err    error  // one of the Err* values above
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("goansi: %s: %v at offset %d", e.Format, e.Err, e.Offset)
+}
+
+// Unwrap returns the underlying error
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// OpError reports a problem that doesn't come from the data of a file, such
+// as an option that can't be used
+type OpError struct {
+	Op  string // operation that failed, e.g. "render"
+	Err error  // one of the Err* values above
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("goansi: %s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// formatError wraps err in a *FormatError
+func formatError(format Format, offset int, err error) error {
+	return &FormatError{Format: format.String(), Offset: int64(offset), Err: err}
+}
@@ -15,132 +15,157 @@
 
 package goansi
 
-// font contains binary font data, size info, etc.
-type font struct {
-	data        []byte
-	sizeX       int
-	sizeY       int
-	isAmigaFont bool
+// Font contains binary font data, size info, etc. Each glyph is Height bytes,
+// one byte per row with the leftmost pixel in the high bit.
+type Font struct {
+	Name   string
+	Data   []byte
+	Width  int  // glyph width in pixels, 9 for PC fonts in real text mode
+	Height int  // glyph height in pixels
+	Amiga  bool // Amiga fonts don't print form feeds and carriage returns
+
+	CodePage int // IBM code page of a PC font, 0 for Amiga fonts
+}
+
+// SelectFont returns one of the embedded fonts by name, falling back to
+// 80x25 for unknown names. The glyph data is shared and must not be modified.
+func SelectFont(fontName string) *Font {
+	var f Font
+	alSelectFont(&f, fontName)
+	return &f
+}
+
+// glyphs returns the 256 glyphs of the font the way files embed them, a byte
+// for every row of each. Glyphs missing from the font data are left blank.
+func (f *Font) glyphs() []byte {
+	data := make([]byte, 256*f.Height)
+	copy(data, f.Data)
+	return data
 }
 
 // AlSelectFont provides choose a font and populates font with that font
-func alSelectFont(f *font, fontName string) {
+func alSelectFont(f *Font, fontName string) {
+	f.Name = fontName
+
 	// determine the font we use to render the output
 	if fontName == "80x25" {
-		f.data = fontPC80x25
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPC80x25
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "80x50" {
-		f.data = fontPC80x50
-		f.sizeX = 9
-		f.sizeY = 8
+		f.Data = fontPC80x50
+		f.Width = 9
+		f.Height = 8
 	} else if fontName == "terminus" {
-		f.data = fontPCTerminus
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCTerminus
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "baltic" {
-		f.data = fontPCBaltic
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCBaltic
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "cyrillic" {
-		f.data = fontPCCyrillic
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCCyrillic
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "french-canadian" {
-		f.data = fontPCFrenchCanadian
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCFrenchCanadian
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "greek" {
-		f.data = fontPCGreek
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCGreek
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "greek-869" {
-		f.data = fontPCGreek869
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCGreek869
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "hebrew" {
-		f.data = fontPCHebrew
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCHebrew
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "icelandic" {
-		f.data = fontPCIcelandic
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCIcelandic
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "latin1" {
-		f.data = fontPCLatin1
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCLatin1
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "latin2" {
-		f.data = fontPCLatin2
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCLatin2
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "nordic" {
-		f.data = fontPCNordic
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCNordic
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "portuguese" {
-		f.data = fontPCPortuguese
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCPortuguese
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "russian" {
-		f.data = fontPCRussian
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCRussian
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "turkish" {
-		f.data = fontPCTurkish
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Data = fontPCTurkish
+		f.Width = 9
+		f.Height = 16
 	} else if fontName == "amiga" {
-		f.isAmigaFont = true
-		f.data = fontAmigaTopaz1200
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaTopaz1200
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "microknight" {
-		f.isAmigaFont = true
-		f.data = fontAmigaMicroknight
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaMicroknight
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "microknight+" {
-		f.isAmigaFont = true
-		f.data = fontAmigaMicroknightPlus
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaMicroknightPlus
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "mosoul" {
-		f.isAmigaFont = true
-		f.data = fontAmigaMosoul
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaMosoul
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "pot-noodle" {
-		f.isAmigaFont = true
-		f.data = fontAmigaPotNoodle
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaPotNoodle
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "topaz" {
-		f.isAmigaFont = true
-		f.data = fontAmigaTopaz1200
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaTopaz1200
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "topaz+" {
-		f.isAmigaFont = true
-		f.data = fontAmigaTopaz1200Plus
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaTopaz1200Plus
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "topaz500" {
-		f.isAmigaFont = true
-		f.data = fontAmigaTopaz500
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaTopaz500
+		f.Width = 8
+		f.Height = 16
 	} else if fontName == "topaz500+" {
-		f.isAmigaFont = true
-		f.data = fontAmigaTopaz500Plus
-		f.sizeX = 8
-		f.sizeY = 16
+		f.Amiga = true
+		f.Data = fontAmigaTopaz500Plus
+		f.Width = 8
+		f.Height = 16
 	} else {
 		// in all other cases use the standard DOS font
-		f.data = fontPC80x25
-		f.sizeX = 9
-		f.sizeY = 16
+		f.Name = "80x25"
+		f.Data = fontPC80x25
+		f.Width = 9
+		f.Height = 16
 	}
+
+	f.CodePage = fontCodePages[f.Name]
 }
 
 var fontPC80x25 = []byte{
@@ -0,0 +1,71 @@
+//  format.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import "strings"
+
+// Format identifies one of the supported file formats
+type Format int
+
+// Supported formats
+const (
+	FormatAuto    Format = iota // not forced, detected from the data
+	FormatANSI                  // ANSi and plain ASCII (.ans, .asc, .nfo, ...)
+	FormatDIZ                   // ANSi cropped to the used width (.diz)
+	FormatPCBoard               // PCBoard @X color codes (.pcb)
+	FormatBinary                // raw character/attribute pairs (.bin)
+	FormatArtworx               // Artworx (.adf)
+	FormatIceDraw               // iCE Draw (.idf)
+	FormatTundra                // TundraDraw 24-bit (.tnd)
+	FormatXBin                  // eXtended BIN (.xb)
+)
+
+var formatNames = [...]string{
+	FormatAuto:    "auto",
+	FormatANSI:    "ANSi",
+	FormatDIZ:     "DIZ",
+	FormatPCBoard: "PCBoard",
+	FormatBinary:  "BIN",
+	FormatArtworx: "ADF",
+	FormatIceDraw: "IDF",
+	FormatTundra:  "Tundra",
+	FormatXBin:    "XBin",
+}
+
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return "unknown"
+	}
+	return formatNames[f]
+}
+
+// FormatFromExt maps a file name extension such as ".xb" to a Format,
+// extensions that don't name a particular format map to FormatAuto
+func FormatFromExt(ext string) Format {
+	switch strings.ToLower(ext) {
+	case ".ans":
+		return FormatANSI
+	case ".pcb":
+		return FormatPCBoard
+	case ".bin":
+		return FormatBinary
+	case ".adf":
+		return FormatArtworx
+	case ".idf":
+		return FormatIceDraw
+	case ".tnd":
+		return FormatTundra
+	case ".xb":
+		return FormatXBin
+	case ".diz":
+		return FormatDIZ
+	}
+	return FormatAuto
+}
@@ -3,63 +3,229 @@ package goansi
 
 import (
 	"bytes"
+	"context"
 	"image"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 
 	"github.com/nfnt/resize"
 )
 
-// Parse takes a buffer of ANSi data and returns an Image.image
-func Parse(inputFileBuffer []byte, inputFileSize int64, fontName string, bits int, columns int, mode string, icecolors bool, fext string, scaleFactor float32) image.Image {
+// RenderOptions controls how Render decodes and draws a file. The zero value
+// renders with the same defaults as the go-ansi command.
+type RenderOptions struct {
+	Format    Format  // file format, FormatAuto picks one from the data
+	FileName  string  // name of the input, its extension is a hint for FormatAuto
+	Font      string  // font name, e.g. "80x25" or "topaz+" (default: 80x25)
+	Bits      int     // character cell width, 8 or 9 (default: 8)
+	Columns   int     // number of columns for BIN and Tundra files (default: 160)
+	Mode      string  // ANSi rendering mode: "ced", "transparent" or "workbench"
+	IceColors bool    // use iCE colors instead of blinking
+	Scale     float32 // scale factor applied to the output image, above 0 (default: 1)
+	Stretch   bool    // stretch the image to the legacy aspect ratio of a 4:3 VGA screen
+	Palette   Palette // ANSi 256 color palette in xterm order (default: XtermPalette())
+
+	// UseSauceHints takes the width, iCE colors, letter spacing, aspect
+	// ratio and font of the SAUCE record for the options left unset.
+	// SkipHints holds the hints not to take, for options that are set
+	// even when they are zero, such as IceColors turned off on purpose.
+	UseSauceHints bool
+	SkipHints     SauceHint
+}
+
+// Result holds the output of Decode and Render
+type Result struct {
+	Canvas *Canvas     // decoded text mode screen
+	Image  image.Image // rendered image, nil when returned by Decode
+	Format Format      // format the data was decoded as
+	Sauce  *Sauce      // SAUCE record of the file, nil if it has none
+	Hints  SauceHint   // options taken from the SAUCE record
+}
+
+// Render reads a complete file from r and renders it to an image. It stops
+// and returns ctx.Err() if ctx is canceled or its deadline passes.
+func Render(ctx context.Context, r io.Reader, opts RenderOptions) (*Result, error) {
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1.0
+	}
+	if !(scale > 0) || math.IsInf(float64(scale), 1) {
+		return nil, &OpError{Op: "render", Err: ErrBadOption}
+	}
+
+	result, err := Decode(ctx, r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stretch := float32(1.0)
+	if opts.Stretch || result.Hints&HintAspect != 0 {
+		stretch = stretchFactor(result.Canvas.Bits)
+	}
+
+	// the image and its scaled copy are allocated at once
+	canvas := result.Canvas
+	width, height := canvas.Width*canvas.Bits, canvas.Height*canvas.Font.Height
+	scaledWidth := float32(width) * scale
+	scaledHeight := float32(height) * scale * stretch
+	if width*height > maxPixels || float64(scaledWidth)*float64(scaledHeight) > maxPixels {
+		return nil, &OpError{Op: "render", Err: ErrTooLarge}
+	}
+
+	outputImg, err := canvas.Draw(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var outputImg image.Image
-	adjustedSize := inputFileSize
-	buf := bytes.NewReader(inputFileBuffer)
-	record := readRecord(buf)
+	result.Image = outputImg
+
+	if scale != 1.0 || stretch != 1.0 {
+		result.Image = resize.Resize(uint(scaledWidth), uint(scaledHeight), outputImg, resize.NearestNeighbor)
+	}
+
+	return result, nil
+}
+
+// Decode reads a complete file from r and decodes it to a Canvas without
+// drawing it. Options that only affect the image, like Scale, are ignored.
+func Decode(ctx context.Context, r io.Reader, opts RenderOptions) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	inputFileBuffer, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.Bits != 0 && opts.Bits != 8 && opts.Bits != 9 {
+		return nil, &OpError{Op: "decode", Err: ErrBadOption}
+	}
+
+	result := &Result{Format: opts.Format}
+
+	var canvas *Canvas
+	record, err := readRecord(bytes.NewReader(inputFileBuffer))
+	if err != nil {
+		return nil, err
+	}
 
 	// if we find a SAUCE record, update bool flag
-	fileHasSAUCE := (record != nil && string(record.Sauceinf.ID[:]) == SauceID)
+	fileHasSAUCE := (string(record.Sauceinf.ID[:]) == SauceID)
 
-	// adjust the file size if file contains a SAUCE record
 	if fileHasSAUCE {
-		adjustedSize -= 129
-		if record.Sauceinf.Comments > 0 {
-			adjustedSize -= int64(5 + 64*record.Sauceinf.Comments)
-		}
-	}
-
-	// create the output file by invoking the appropiate function
-	if fext == ".pcb" {
-		// params: input, output, font, bits
-		outputImg = pcboard(inputFileBuffer, adjustedSize, fontName, bits, icecolors)
-	} else if fext == ".bin" {
-		// params: input, output, columns, font, bits, icecolors
-		outputImg = binfile(inputFileBuffer, adjustedSize, columns, fontName, bits, icecolors)
-	} else if fext == ".adf" {
-		// params: input, output, bits
-		outputImg = artworx(inputFileBuffer, adjustedSize)
-	} else if fext == ".idf" {
-		// params: input, output, bits
-		outputImg = icedraw(inputFileBuffer, adjustedSize)
-	} else if fext == ".tnd" {
-		outputImg = tundra(inputFileBuffer, adjustedSize, columns, fontName, bits)
-	} else if fext == ".xb" {
-		// params: input, output, bits
-		outputImg = xbin(inputFileBuffer, adjustedSize)
-	} else {
-		// params: input, output, font, bits, icecolors, fext
-		outputImg = ansi(inputFileBuffer, adjustedSize, fontName, bits, mode, icecolors, fext)
-	}
-
-	if scaleFactor != 1.0 && outputImg != nil {
-		scaledHeight := float32(outputImg.Bounds().Max.Y) * scaleFactor
-		scaledWidth := float32(outputImg.Bounds().Max.X) * scaleFactor
-		outputImg = resize.Resize(uint(scaledWidth), uint(scaledHeight), outputImg, resize.NearestNeighbor)
-	}
-
-	return outputImg
+		result.Sauce = record
+	}
+
+	// adjust the file size if file contains a SAUCE record
+	adjustedSize := int64(sauceDataSize(inputFileBuffer, record))
+
+	if result.Format == FormatAuto {
+		result.Format = detectFormat(inputFileBuffer[:adjustedSize], record, filepath.Ext(opts.FileName))
+	}
+
+	// the width of ANSi files only comes from the record
+	ansiWidth := ansiColumns
+	if opts.UseSauceHints && fileHasSAUCE {
+		result.Hints = sauceHints(&opts, &ansiWidth, &record.Sauceinf, result.Format)
+	}
+
+	// defaults matching the go-ansi command
+	if opts.Bits == 0 {
+		opts.Bits = 8
+	}
+	if opts.Columns == 0 {
+		opts.Columns = 160
+	}
+
+	// decode the file by invoking the appropiate function
+	switch result.Format {
+	case FormatPCBoard:
+		canvas, err = pcboard(ctx, inputFileBuffer, adjustedSize, opts.Font, opts.Bits, opts.IceColors)
+	case FormatBinary:
+		canvas, err = binfile(ctx, inputFileBuffer, adjustedSize, opts.Columns, opts.Font, opts.Bits, opts.IceColors)
+	case FormatArtworx:
+		canvas, err = artworx(ctx, inputFileBuffer, adjustedSize)
+	case FormatIceDraw:
+		canvas, err = icedraw(ctx, inputFileBuffer, adjustedSize)
+	case FormatTundra:
+		canvas, err = tundra(ctx, inputFileBuffer, adjustedSize, opts.Columns, opts.Font, opts.Bits)
+	case FormatXBin:
+		canvas, err = xbin(ctx, inputFileBuffer, adjustedSize)
+	default:
+		canvas, err = ansi(ctx, inputFileBuffer, adjustedSize, ansiWidth, opts.Font, opts.Bits, opts.Mode, opts.IceColors, result.Format == FormatDIZ, opts.Palette)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Canvas = canvas
+
+	return result, nil
+}
+
+// Parse takes a buffer of ANSi data and returns an Image.image, or nil if
+// it can't be decoded.
+//
+// Deprecated: Parse hides the errors of the decoders, use ParseImage or
+// Render.
+func Parse(inputFileBuffer []byte, inputFileSize int64, fontName string, bits int, columns int, mode string, icecolors bool, fext string, scaleFactor float32) image.Image {
+	img, _ := ParseImage(inputFileBuffer, inputFileSize, fontName, bits, columns, mode, icecolors, fext, scaleFactor)
+	return img
 }
 
-// GetSauce returns a sauce record for a given file if it exists
+// ParseImage takes a buffer of ANSi data and returns an Image.image, with
+// the arguments of Parse. The format is detected from the data, fext is
+// only consulted when that is inconclusive.
+func ParseImage(inputFileBuffer []byte, inputFileSize int64, fontName string, bits int, columns int, mode string, icecolors bool, fext string, scaleFactor float32) (image.Image, error) {
+	if inputFileSize < 0 || inputFileSize > int64(len(inputFileBuffer)) {
+		return nil, &OpError{Op: "parse", Err: ErrTruncated}
+	}
+
+	result, err := Render(context.Background(), bytes.NewReader(inputFileBuffer[:inputFileSize]), RenderOptions{
+		FileName:  fext,
+		Font:      fontName,
+		Bits:      bits,
+		Columns:   columns,
+		Mode:      mode,
+		IceColors: icecolors,
+		Scale:     scaleFactor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Image, nil
+}
+
+// GetSauce returns the SAUCE record of a file, or an empty one if it has
+// none or can't be read.
+//
+// Deprecated: GetSauce hides the errors, use ReadSauceFile.
 func GetSauce(fileName string) Sauce {
-	return *readFileName(fileName)
+	record, err := ReadSauceFile(fileName)
+	if err != nil {
+		return Sauce{}
+	}
+	return *record
+}
+
+// ReadSauceFile reads the SAUCE record at the end of the named file, like
+// ReadSauce
+func ReadSauceFile(fileName string) (*Sauce, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadSauce(f)
 }
@@ -0,0 +1,105 @@
+//  goansi_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte("\x1b[44mhello")
+
+	img, err := ParseImage(data, int64(len(data)), "80x25", 8, 160, "", false, ".ans", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Parse(data, int64(len(data)), "80x25", 8, 160, "", false, ".ans", 1); got.Bounds() != img.Bounds() {
+		t.Errorf("Parse: got bounds %v, want %v", got.Bounds(), img.Bounds())
+	}
+
+	// the errors ParseImage returns, Parse hides
+	if _, err := ParseImage(data, 100, "80x25", 8, 160, "", false, ".ans", 1); !errors.Is(err, ErrTruncated) {
+		t.Errorf("ParseImage: got %v, want ErrTruncated", err)
+	}
+	if got := Parse(data, 100, "80x25", 8, 160, "", false, ".ans", 1); got != nil {
+		t.Errorf("Parse: got an image of %v, want nil", got.Bounds())
+	}
+}
+
+func TestGetSauce(t *testing.T) {
+	dir := t.TempDir()
+
+	var record Sauce
+	record.SetTitle("Title")
+
+	var buf bytes.Buffer
+	buf.WriteString("data")
+	if err := WriteSauce(&buf, record); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "test.ans")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ReadSauceFile(path); err != nil || got.Title() != "Title" {
+		t.Errorf("ReadSauceFile: got %v, %v", got, err)
+	}
+	if got := GetSauce(path); got.Title() != "Title" {
+		t.Errorf("GetSauce: got title %q", got.Title())
+	}
+
+	// a missing file has no record
+	missing := filepath.Join(dir, "missing")
+	if _, err := ReadSauceFile(missing); !os.IsNotExist(err) {
+		t.Errorf("ReadSauceFile: got %v", err)
+	}
+	if got := GetSauce(missing); got.Title() != "" {
+		t.Errorf("GetSauce: got title %q", got.Title())
+	}
+}
+
+func TestRenderOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts RenderOptions
+		err  error
+	}{
+		{"negative scale", "x", RenderOptions{Scale: -1}, ErrBadOption},
+		{"NaN scale", "x", RenderOptions{Scale: float32(math.NaN())}, ErrBadOption},
+		{"infinite scale", "x", RenderOptions{Scale: float32(math.Inf(1))}, ErrBadOption},
+		{"negative bits", "x", RenderOptions{Bits: -3}, ErrBadOption},
+		{"1 bit", "x", RenderOptions{Bits: 1}, ErrBadOption},
+		{"16 bits", "x", RenderOptions{Bits: 16}, ErrBadOption},
+		{"defaults", "x", RenderOptions{}, nil},
+		{"9 bits", "x", RenderOptions{Bits: 9, Scale: 0.5}, nil},
+		{"too many pixels", "x", RenderOptions{Scale: 1000}, ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		_, err := Render(context.Background(), strings.NewReader(tt.data), tt.opts)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	// bits are checked without drawing too
+	if _, err := Decode(context.Background(), strings.NewReader("x"), RenderOptions{Bits: 16}); !errors.Is(err, ErrBadOption) {
+		t.Errorf("Decode: got %v, want ErrBadOption", err)
+	}
+}
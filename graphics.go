@@ -0,0 +1,140 @@
+//  graphics.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+)
+
+// EncodeSixel writes im as DEC Sixel graphics, for terminals that show
+// images inline. Images with more than 256 colors are reduced to a fixed
+// palette. Transparent pixels are left alone.
+func EncodeSixel(w io.Writer, im image.Image) error {
+	p := quantize(im)
+	b := p.Rect
+
+	bw := bufio.NewWriter(w)
+
+	// pixels that aren't drawn stay transparent, square pixels
+	fmt.Fprintf(bw, "\x1bP0;1q\"1;1;%d;%d", b.Dx(), b.Dy())
+
+	// sixel colors are percentages
+	for i, c := range p.Palette {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, (r*100+0x7FFF)/0xFFFF, (g*100+0x7FFF)/0xFFFF, (bl*100+0x7FFF)/0xFFFF)
+	}
+
+	// a band of six rows at a time, each color drawn over the band in turn
+	bands := make([][]byte, len(p.Palette))
+
+	for y := b.Min.Y; y < b.Max.Y; y += 6 {
+		var used []int
+
+		for dy := 0; dy < 6 && y+dy < b.Max.Y; dy++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				i := p.ColorIndexAt(x, y+dy)
+				if _, _, _, a := p.Palette[i].RGBA(); a < 0x8000 {
+					continue
+				}
+
+				if bands[i] == nil {
+					bands[i] = make([]byte, b.Dx())
+					used = append(used, int(i))
+				}
+				bands[i][x-b.Min.X] |= 1 << uint(dy)
+			}
+		}
+
+		if y > b.Min.Y {
+			bw.WriteByte('-')
+		}
+
+		for n, i := range used {
+			if n > 0 {
+				bw.WriteByte('$')
+			}
+			fmt.Fprintf(bw, "#%d", i)
+			writeSixels(bw, bands[i])
+			bands[i] = nil
+		}
+	}
+
+	bw.WriteString("\x1b\\")
+
+	return bw.Flush()
+}
+
+// writeSixels writes a row of sixels, repeats of the same one compressed,
+// leaving out the empty ones at the end
+func writeSixels(bw *bufio.Writer, sixels []byte) {
+	end := len(sixels)
+	for end > 0 && sixels[end-1] == 0 {
+		end--
+	}
+
+	for x := 0; x < end; {
+		n := 1
+		for x+n < end && sixels[x+n] == sixels[x] {
+			n++
+		}
+
+		char := '?' + sixels[x]
+		if n > 3 {
+			fmt.Fprintf(bw, "!%d%c", n, char)
+		} else {
+			for i := 0; i < n; i++ {
+				bw.WriteByte(char)
+			}
+		}
+
+		x += n
+	}
+}
+
+// kittyChunk is the most base64 data a kitty graphics escape may carry
+const kittyChunk = 4096
+
+// EncodeKitty writes im as kitty graphics protocol escapes, which show it
+// inline in terminals that support them. The image is sent as a PNG, the
+// terminal is asked not to answer.
+func EncodeKitty(w io.Writer, im image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, im); err != nil {
+		return err
+	}
+
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	bw := bufio.NewWriter(w)
+
+	for i := 0; i == 0 || i < len(data); i += kittyChunk {
+		end := min(i+kittyChunk, len(data))
+
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+
+		// only the first chunk has the keys of the image
+		if i == 0 {
+			fmt.Fprintf(bw, "\x1b_Ga=T,f=100,q=2,m=%d;%s\x1b\\", more, data[i:end])
+		} else {
+			fmt.Fprintf(bw, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+
+	return bw.Flush()
+}
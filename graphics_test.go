@@ -0,0 +1,213 @@
+//  graphics_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// sixelPercent is a color as the percentages sixel colors are given in
+type sixelPercent [3]int
+
+func toSixelPercent(c color.Color) sixelPercent {
+	r, g, b, _ := c.RGBA()
+	return sixelPercent{int((r*100 + 0x7FFF) / 0xFFFF), int((g*100 + 0x7FFF) / 0xFFFF), int((b*100 + 0x7FFF) / 0xFFFF)}
+}
+
+// decodeSixel reads back the output of EncodeSixel: the size from the raster
+// attributes, the colors and the bands. Pixels that aren't drawn are
+// missing from the result.
+func decodeSixel(t *testing.T, data string) (width, height int, pixels map[image.Point]sixelPercent) {
+	t.Helper()
+
+	if !strings.HasPrefix(data, "\x1bP0;1q\"1;1;") || !strings.HasSuffix(data, "\x1b\\") {
+		t.Fatalf("not a sixel image: %q", data)
+	}
+	data = strings.TrimSuffix(strings.TrimPrefix(data, "\x1bP0;1q\"1;1;"), "\x1b\\")
+
+	number := func() int {
+		n := 0
+		for len(data) > 0 && data[0] >= '0' && data[0] <= '9' {
+			n = n*10 + int(data[0]-'0')
+			data = data[1:]
+		}
+		return n
+	}
+
+	width = number()
+	data = data[1:]
+	height = number()
+
+	colors := make(map[int]sixelPercent)
+	pixels = make(map[image.Point]sixelPercent)
+	current, x, y := 0, 0, 0
+
+	for len(data) > 0 {
+		c := data[0]
+		data = data[1:]
+
+		switch {
+		case c == '#':
+			current = number()
+			if strings.HasPrefix(data, ";2;") {
+				data = data[3:]
+				var p sixelPercent
+				for i := range p {
+					p[i] = number()
+					if i < 2 {
+						data = data[1:]
+					}
+				}
+				colors[current] = p
+			}
+		case c == '$':
+			x = 0
+		case c == '-':
+			x = 0
+			y += 6
+		case c == '!' || (c >= '?' && c <= '~'):
+			n := 1
+			if c == '!' {
+				n = number()
+				c = data[0]
+				data = data[1:]
+			}
+			for ; n > 0; n-- {
+				for dy := 0; dy < 6; dy++ {
+					if (c-'?')&(1<<uint(dy)) != 0 {
+						pixels[image.Pt(x, y+dy)] = colors[current]
+					}
+				}
+				x++
+			}
+		default:
+			t.Fatalf("unexpected %q in sixel data", c)
+		}
+	}
+
+	return width, height, pixels
+}
+
+// testImage returns an image of a few colors in runs of different lengths,
+// with a transparent corner
+func testImage(width, height int) *image.RGBA {
+	colors := []color.RGBA{{0, 0, 0, 255}, {170, 0, 0, 255}, {85, 255, 255, 255}, {255, 255, 255, 255}}
+
+	im := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			im.SetRGBA(x, y, colors[(x/(y%5+1)+y)%len(colors)])
+		}
+	}
+	im.SetRGBA(0, 0, color.RGBA{})
+	return im
+}
+
+func TestEncodeSixel(t *testing.T) {
+	// a height that leaves the last band short
+	im := testImage(37, 13)
+
+	var buf bytes.Buffer
+	if err := EncodeSixel(&buf, im); err != nil {
+		t.Fatal(err)
+	}
+
+	width, height, pixels := decodeSixel(t, buf.String())
+	if width != 37 || height != 13 {
+		t.Fatalf("got a %dx%d image, want 37x13", width, height)
+	}
+
+	// sixel colors are percentages, the pixels are compared at that precision
+	for y := 0; y < height+6; y++ {
+		for x := 0; x < width; x++ {
+			got, drawn := pixels[image.Pt(x, y)]
+
+			c := im.RGBAAt(x, y)
+			if !image.Pt(x, y).In(im.Bounds()) || c.A == 0 {
+				if drawn {
+					t.Errorf("%d,%d: got %v, want nothing drawn", x, y, got)
+				}
+				continue
+			}
+
+			if want := toSixelPercent(c); got != want {
+				t.Errorf("%d,%d: got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+var kittyEscape = regexp.MustCompile(`\x1b_G([^;]*);([^\x1b]*)\x1b\\`)
+
+func TestEncodeKitty(t *testing.T) {
+	// noise, which doesn't compress into a single chunk
+	rnd := rand.New(rand.NewSource(1))
+	im := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	rnd.Read(im.Pix)
+	for i := 3; i < len(im.Pix); i += 4 {
+		im.Pix[i] = 255
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeKitty(&buf, im); err != nil {
+		t.Fatal(err)
+	}
+
+	escapes := kittyEscape.FindAllStringSubmatch(buf.String(), -1)
+	if len(escapes) < 2 || len(strings.Join(kittyEscape.Split(buf.String(), -1), "")) != 0 {
+		t.Fatalf("got %d escapes and other data, want chunks only", len(escapes))
+	}
+
+	var payload string
+	for i, e := range escapes {
+		keys, chunk := e[1], e[2]
+
+		more := "m=1"
+		if i == len(escapes)-1 {
+			more = "m=0"
+		}
+		if i == 0 && keys != "a=T,f=100,q=2,"+more || i > 0 && keys != more {
+			t.Errorf("chunk %d: got keys %q", i, keys)
+		}
+		if len(chunk) > kittyChunk {
+			t.Errorf("chunk %d: %d bytes of data, want at most %d", i, len(chunk), kittyChunk)
+		}
+
+		payload += chunk
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Bounds() != im.Bounds() {
+		t.Fatalf("got bounds %v, want %v", decoded.Bounds(), im.Bounds())
+	}
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			if got, want := color.RGBAModel.Convert(decoded.At(x, y)), im.At(x, y); got != want {
+				t.Fatalf("%d,%d: got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
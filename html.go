@@ -0,0 +1,243 @@
+//  html.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// HTMLOptions controls EncodeHTML
+type HTMLOptions struct {
+	Title  string // page title (default: "ANSi")
+	Sprite bool   // draw the glyphs of the canvas font, embedded as a CSS sprite
+}
+
+// htmlAttrs are the attributes a span shows itself, the others are part of
+// its colors
+const htmlAttrs = AttrItalic | AttrUnderline | AttrStrike | AttrBlink
+
+// glyphAttrs are the attributes drawn into glyphs by alDrawChar
+const glyphAttrs = AttrItalic | AttrUnderline | AttrStrike
+
+// cellStyle is what the cells of a run share
+type cellStyle struct {
+	fg, bg color.RGBA
+	attr   Attr
+}
+
+// glyphKey is a character drawn with some attributes
+type glyphKey struct {
+	char byte
+	attr Attr
+}
+
+// EncodeHTML writes the canvas as an HTML page holding a <pre> element, with
+// a span for every run of cells that share their colors and attributes.
+// Characters are mapped from the code page of the font to Unicode, so the
+// text can be selected and searched. Without a sprite the browser draws the
+// text in a monospace font. Blinking text blinks at the VGA rate.
+func (c *Canvas) EncodeHTML(w io.Writer, opts HTMLOptions) error {
+	bits := c.Bits
+	if bits == 0 {
+		bits = 8
+	}
+
+	if opts.Title == "" {
+		opts.Title = "ANSi"
+	}
+
+	colors := make(map[color.RGBA]int)
+	glyphs := make(map[glyphKey]int)
+	var glyphList []glyphKey
+
+	colorClass := func(prefix string, rgba color.RGBA) string {
+		i, ok := colors[rgba]
+		if !ok {
+			i = len(colors)
+			colors[rgba] = i
+		}
+		return fmt.Sprintf("%s%d", prefix, i)
+	}
+
+	var text bytes.Buffer
+
+	for y := 0; y < c.Height; y++ {
+		var prev cellStyle
+
+		for x := 0; x < c.Width; x++ {
+			cell := c.Cells[y*c.Width+x]
+			fg, bg := c.CellColors(cell)
+			style := cellStyle{fg, bg, cell.Attr & htmlAttrs}
+
+			if x == 0 || style != prev {
+				if x > 0 {
+					text.WriteString("</span>")
+				}
+
+				classes := []string{colorClass("f", fg)}
+				if bg.A > 0 {
+					classes = append(classes, colorClass("b", bg))
+				}
+				if !opts.Sprite {
+					if style.attr&AttrItalic != 0 {
+						classes = append(classes, "it")
+					}
+					if style.attr&AttrUnderline != 0 {
+						classes = append(classes, "ul")
+					}
+					if style.attr&AttrStrike != 0 {
+						classes = append(classes, "st")
+					}
+				}
+				if style.attr&AttrBlink != 0 {
+					classes = append(classes, "bl")
+				}
+
+				fmt.Fprintf(&text, "<span class=\"%s\">", strings.Join(classes, " "))
+				prev = style
+			}
+
+			if opts.Sprite {
+				glyph := glyphKey{cell.Char, cell.Attr & glyphAttrs}
+				i, ok := glyphs[glyph]
+				if !ok {
+					i = len(glyphList)
+					glyphs[glyph] = i
+					glyphList = append(glyphList, glyph)
+				}
+				fmt.Fprintf(&text, "<i class=\"g%d\">", i)
+			}
+
+			switch r := c.Font.toUnicode(cell.Char); r {
+			case '<':
+				text.WriteString("&lt;")
+			case '>':
+				text.WriteString("&gt;")
+			case '&':
+				text.WriteString("&amp;")
+			default:
+				text.WriteRune(r)
+			}
+
+			if opts.Sprite {
+				text.WriteString("</i>")
+			}
+		}
+
+		if c.Width > 0 {
+			text.WriteString("</span>")
+		}
+		text.WriteString("\n")
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n",
+		htmlEscaper.Replace(opts.Title))
+
+	background := "transparent"
+	if !c.Transparent {
+		background = cssColor(c.paletteColor(0))
+	}
+
+	height := c.Font.Height
+
+	if opts.Sprite {
+		sprite, err := c.sprite(glyphList, bits)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, "pre.ansi{margin:0;font-size:%dpx;line-height:%dpx;background:%s;"+
+			"--sprite:url(data:image/png;base64,%s)}\n", height, height, background, sprite)
+		fmt.Fprintf(bw, "pre.ansi span{display:inline-block;height:%dpx;vertical-align:top}\n", height)
+		fmt.Fprintf(bw, "pre.ansi i{display:inline-block;width:%dpx;height:%dpx;vertical-align:top;"+
+			"font-style:normal;-webkit-text-fill-color:transparent;background-color:currentColor;"+
+			"-webkit-mask-image:var(--sprite);mask-image:var(--sprite);"+
+			"-webkit-mask-repeat:no-repeat;mask-repeat:no-repeat}\n", bits, height)
+
+		for i := range glyphList {
+			x, y := spriteOffset(i, bits, height)
+			fmt.Fprintf(bw, ".g%d{-webkit-mask-position:-%dpx -%dpx;mask-position:-%dpx -%dpx}\n", i, x, y, x, y)
+		}
+	} else {
+		fmt.Fprintf(bw, "pre.ansi{margin:0;font-family:monospace;line-height:1;background:%s}\n", background)
+		bw.WriteString(".it{font-style:italic}\n" +
+			".ul{text-decoration:underline}\n" +
+			".st{text-decoration:line-through}\n" +
+			".ul.st{text-decoration:underline line-through}\n")
+	}
+
+	// visible for the first half of the cycle, hidden for the second
+	fmt.Fprintf(bw, ".bl{animation:blink %dms step-end infinite}\n", 2*BlinkDelay.Nanoseconds()/1e6)
+	bw.WriteString("@keyframes blink{50%{color:transparent}}\n")
+
+	colorList := make([]color.RGBA, len(colors))
+	for rgba, i := range colors {
+		colorList[i] = rgba
+	}
+	for i, rgba := range colorList {
+		fmt.Fprintf(bw, ".f%d{color:%s}.b%d{background-color:%s}\n", i, cssColor(rgba), i, cssColor(rgba))
+	}
+
+	bw.WriteString("</style>\n</head>\n<body>\n<pre class=\"ansi\">")
+	bw.Write(text.Bytes())
+	bw.WriteString("</pre>\n</body>\n</html>\n")
+
+	return bw.Flush()
+}
+
+// htmlEscaper escapes text outside of the art
+var htmlEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;", "&", "&amp;", "\"", "&quot;")
+
+// cssColor formats an opaque color as #rrggbb
+func cssColor(rgba color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
+
+// spriteColumns is the number of glyphs in a row of the sprite
+const spriteColumns = 32
+
+// spriteOffset returns where glyph i is in the sprite
+func spriteOffset(i, bits, height int) (x, y int) {
+	return (i % spriteColumns) * bits, (i / spriteColumns) * height
+}
+
+// sprite draws glyphs in white on transparent, the mask of the text, and
+// returns it as a base64 encoded PNG
+func (c *Canvas) sprite(glyphs []glyphKey, bits int) (string, error) {
+	height := c.Font.Height
+
+	columns := min(len(glyphs), spriteColumns)
+	rows := (len(glyphs) + spriteColumns - 1) / spriteColumns
+
+	im := image.NewRGBA(image.Rect(0, 0, max(columns, 1)*bits, max(rows, 1)*height))
+	white := color.RGBA{255, 255, 255, 255}
+
+	for i, glyph := range glyphs {
+		alDrawChar(im, c.Font.Data, bits, height, i%spriteColumns, i/spriteColumns,
+			color.RGBA{}, white, glyph.char, glyph.attr)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, im); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -16,29 +16,37 @@
 package goansi
 
 import (
+	"context"
 	"encoding/binary"
-	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"os"
 )
 
-func icedraw(inputFileBuffer []byte, inputFileSize int64) image.Image {
+// idfMaxColumns is the widest canvas accepted from an IDF header
+const idfMaxColumns = 8192
+
+func icedraw(ctx context.Context, inputFileBuffer []byte, inputFileSize int64) (*Canvas, error) {
+	// header, 256 character font and 16 color palette
+	if inputFileSize < 12+4096+48 {
+		return nil, formatError(FormatIceDraw, int(inputFileSize), ErrTruncated)
+	}
+
 	// extract relevant part of the IDF header, 16-bit little-endian unsigned short
 	var byteBuf = []byte{inputFileBuffer[8], inputFileBuffer[9]}
 	x2 := binary.LittleEndian.Uint16(byteBuf)
+	columns := int(x2) + 1
 
-	// libgd image pointers
-	var imIDF draw.Image
+	if columns > idfMaxColumns {
+		return nil, formatError(FormatIceDraw, 8, ErrBadHeader)
+	}
 
 	var loop int
-	var index int
-	var colors [16]color.RGBA
 
 	offset := inputFileSize - 48 - 4096
 
-	fontData := inputFileBuffer[offset : offset+4096]
+	f := Font{
+		Data:   append([]byte(nil), inputFileBuffer[offset:offset+4096]...),
+		Width:  8,
+		Height: 16,
+	}
 
 	// process IDF
 	loop = 12
@@ -49,12 +57,16 @@ func icedraw(inputFileBuffer []byte, inputFileSize int64) image.Image {
 
 	var idfData, idfDataLength int16
 
-	for loop < (int(inputFileSize) - 4096 - 48) {
+	for loop+1 < (int(inputFileSize) - 4096 - 48) {
 		var byteBuf = []byte{inputFileBuffer[loop], inputFileBuffer[loop+1]}
 		idfData = int16(binary.LittleEndian.Uint16(byteBuf))
 
 		// RLE compressed data
 		if idfData == 1 {
+			if loop+5 >= int(inputFileSize)-4096-48 {
+				return nil, formatError(FormatIceDraw, loop, ErrTruncated)
+			}
+
 			var byteBuf = []byte{inputFileBuffer[loop+2], inputFileBuffer[loop+3]}
 			idfDataLength = int16(binary.LittleEndian.Uint16(byteBuf))
 			idfSequenceLength = int(idfDataLength & 255)
@@ -69,35 +81,30 @@ func icedraw(inputFileBuffer []byte, inputFileSize int64) image.Image {
 			idfBuffer = append(idfBuffer, inputFileBuffer[loop])
 			idfBuffer = append(idfBuffer, inputFileBuffer[loop+1])
 		}
-		loop += 2
-	}
 
-	// create IDF instance
-	imIDF = image.NewRGBA(image.Rect(0, 0, int((x2+1)*8), len(idfBuffer)/2/80*16))
-
-	if imIDF == nil {
-		fmt.Printf("\nError, can't allocate buffer image memory.\n\n")
-		os.Exit(6)
+		// runs can't expand past the cells a canvas may have
+		if len(idfBuffer)/2 > maxCells {
+			return nil, formatError(FormatIceDraw, loop, ErrBadHeader)
+		}
+		loop += 2
 	}
 
-	black := color.RGBA{0, 0, 0, 255}
-	draw.Draw(imIDF, imIDF.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
-
 	// process IDF palette
-	for loop = 0; loop < 16; loop++ {
-		index = (loop * 3) + int(inputFileSize) - 48
-		r := (inputFileBuffer[index]<<2 | inputFileBuffer[index]>>4)
-		g := (inputFileBuffer[index+1]<<2 | inputFileBuffer[index+1]>>4)
-		b := (inputFileBuffer[index+2]<<2 | inputFileBuffer[index+2]>>4)
-		colors[loop] = color.RGBA{r, g, b, 255}
+	colors := readVGAPalette(inputFileBuffer[inputFileSize-48:], 16)
+
+	// create IDF instance
+	rows := len(idfBuffer) / 2 / columns
+	if !canvasFits(columns, rows) {
+		return nil, formatError(FormatIceDraw, 8, ErrBadHeader)
 	}
+	canvas := NewCanvas(columns, rows, &f, colors)
 
 	// render IDF
 	var positionX, positionY int
 	var character, attribute, colorForeground, colorBackground int
 
 	for loop = 0; loop < len(idfBuffer); loop += 2 {
-		if positionX == int(x2+1) {
+		if positionX == columns {
 			positionX = 0
 			positionY++
 		}
@@ -108,11 +115,15 @@ func icedraw(inputFileBuffer []byte, inputFileSize int64) image.Image {
 		colorBackground = (attribute & 240) >> 4
 		colorForeground = attribute & 15
 
-		alDrawChar(imIDF, fontData, 8, 16, positionX, positionY, colors[colorBackground], colors[colorForeground], byte(character))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		canvas.Set(positionX, positionY, Cell{Char: byte(character), Fg: colorForeground, Bg: colorBackground})
 
 		positionX++
 	}
 
-	// return IDF image
-	return imIDF
+	// return IDF canvas
+	return canvas, nil
 }
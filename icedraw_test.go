@@ -0,0 +1,53 @@
+//  icedraw_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// idfFile returns an IDF file of the given columns around data, with a blank
+// font and palette
+func idfFile(columns int, data []byte) []byte {
+	header := []byte("\x041.4\x00\x00\x00\x00\x00\x00\x00\x00")
+	header[8], header[9] = byte(columns-1), byte((columns-1)>>8)
+
+	file := append(header, data...)
+	return append(file, make([]byte, 4096+48)...)
+}
+
+func TestIceDrawRuns(t *testing.T) {
+	// a run of 3 cells and a character, and a run to the end of the row
+	data := []byte{1, 0, 3, 0, 'A', 0x1F, 'B', 0x2E, 1, 0, 76, 0, ' ', 7}
+	result, err := Decode(context.Background(), bytes.NewReader(idfFile(80, data)), RenderOptions{Format: FormatIceDraw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := result.Canvas
+	if c.Width != 80 || c.Height != 1 {
+		t.Fatalf("got %dx%d, want 80x1", c.Width, c.Height)
+	}
+	want := []Cell{{Char: 'A', Fg: 15, Bg: 1}, {Char: 'A', Fg: 15, Bg: 1}, {Char: 'A', Fg: 15, Bg: 1}, {Char: 'B', Fg: 14, Bg: 2}}
+	for i, cell := range want {
+		if got := c.Cells[i]; got.Char != cell.Char || got.Fg != cell.Fg || got.Bg != cell.Bg {
+			t.Errorf("cell %d is %+v, want %+v", i, got, cell)
+		}
+	}
+
+	// runs expanding to more cells than a canvas may have
+	runs := bytes.Repeat([]byte{1, 0, 255, 0, 'X', 7}, maxCells/255+1)
+	_, err = Decode(context.Background(), bytes.NewReader(idfFile(80, runs)), RenderOptions{Format: FormatIceDraw})
+	if !errors.Is(err, ErrBadHeader) {
+		t.Errorf("long runs: got %v, want ErrBadHeader", err)
+	}
+}
@@ -0,0 +1,61 @@
+//  icedraww.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// idfMaxRun is the longest run of a cell an IDF RLE word holds
+const idfMaxRun = 255
+
+// EncodeIDF writes the canvas as an iCEDraw IDF file, run length encoded,
+// with its font and the first 16 colors of its palette embedded. IDF files
+// have an 8x16 font and are always read with iCE colors, so nothing blinks.
+func (c *Canvas) EncodeIDF(w io.Writer) error {
+	if c.Width < 1 || c.Width > idfMaxColumns || c.Height > 0x10000 || c.Font.Height != 16 {
+		return formatError(FormatIceDraw, 0, ErrBadOption)
+	}
+
+	colors := vgaPaletteBytes(c.Palette, 16)
+
+	// the window the data covers, x1 and y1 are always 0
+	buf := make([]byte, 12)
+	copy(buf, idfSignature)
+	binary.LittleEndian.PutUint16(buf[8:], uint16(c.Width-1))
+	binary.LittleEndian.PutUint16(buf[10:], uint16(max(c.Height-1, 0)))
+
+	data := c.textData(c.Width, readVGAPalette(colors, 16), false)
+
+	for i := 0; i < len(data); {
+		n := 1
+		for i+n*2 < len(data) && n < idfMaxRun && data[i+n*2] == data[i] && data[i+n*2+1] == data[i+1] {
+			n++
+		}
+
+		// a run is the word 1 followed by its length and the cell, so a
+		// cell that reads as 1 has to be a run too
+		if n > 3 || (data[i] == 1 && data[i+1] == 0) {
+			buf = append(buf, 1, 0, byte(n), 0, data[i], data[i+1])
+			i += n * 2
+			continue
+		}
+
+		buf = append(buf, data[i], data[i+1])
+		i += 2
+	}
+
+	buf = append(buf, c.Font.glyphs()...)
+	buf = append(buf, colors...)
+
+	_, err := w.Write(buf)
+	return err
+}
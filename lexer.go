@@ -0,0 +1,325 @@
+//  lexer.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import "bytes"
+
+// limits that keep hostile sequences from using up memory or overflowing
+const (
+	csiMaxParams = 64     // further parameters are dropped
+	csiMaxParam  = 0xFFFF // larger values are clamped
+)
+
+// EventKind tells what an Event stands for
+type EventKind int
+
+// Event kinds
+const (
+	EventPrint   EventKind = iota // a character to draw
+	EventControl                  // a C0 control character, 0x00-0x1F
+	EventESC                      // an escape sequence other than CSI
+	EventCSI                      // a control sequence, ESC [ ... final
+	EventSauce                    // the EOF character and SAUCE record ending the data
+	EventEOF                      // the end of the data
+)
+
+var eventKindNames = [...]string{
+	EventPrint:   "Print",
+	EventControl: "Control",
+	EventESC:     "ESC",
+	EventCSI:     "CSI",
+	EventSauce:   "SAUCE",
+	EventEOF:     "EOF",
+}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventKindNames) {
+		return "unknown"
+	}
+	return eventKindNames[k]
+}
+
+// Event is a single character or escape sequence found in ANSi data
+type Event struct {
+	Kind          EventKind
+	Offset        int64   // offset of the first byte of the event
+	Char          byte    // the character, or the final byte of a sequence
+	Private       byte    // CSI private marker, one of < = > ? or 0
+	Params        []int   // CSI parameters, omitted ones are 0
+	Sub           [][]int // colon separated sub-parameters following each parameter
+	Intermediates []byte  // intermediate bytes, 0x20-0x2F
+	Sauce         *Sauce  // the record of an EventSauce
+}
+
+// Param returns parameter i of a CSI event, or def if it is omitted or 0
+func (e *Event) Param(i, def int) int {
+	if i >= len(e.Params) || e.Params[i] == 0 {
+		return def
+	}
+	return e.Params[i]
+}
+
+// lexer states
+const (
+	stateGround = iota
+	stateEscape
+	stateCSIParam
+	stateCSIIntermediate
+	stateCSIIgnore
+)
+
+// lexer splits ANSi data into events following ECMA-48. It is fed one byte
+// at a time so sequences may be split across writes. Malformed sequences are
+// dropped, bytes that can't be part of a sequence are handled as if the
+// sequence had not been there.
+type lexer struct {
+	state  int
+	offset int64 // offset of the next byte
+	seq    Event // sequence being collected
+	count  int   // parameters seen so far, including dropped ones
+	sub    int   // sub-parameters of the current parameter seen so far
+}
+
+// feed processes the next byte and calls emit for every event it completes.
+// A byte can complete two events when it cuts a sequence short. The event
+// is only valid until emit returns.
+func (l *lexer) feed(c byte, emit func(*Event)) {
+	offset := l.offset
+	l.offset++
+
+	switch l.state {
+	case stateGround:
+		l.ground(c, offset, emit)
+
+	case stateEscape:
+		switch {
+		case c == '[' && len(l.seq.Intermediates) == 0:
+			l.state = stateCSIParam
+		case c >= 0x20 && c <= 0x2F:
+			l.seq.Intermediates = append(l.seq.Intermediates, c)
+		case c == 24 || c == 26:
+			// CAN and SUB cancel the sequence
+			l.state = stateGround
+		case c >= 0x30 && c <= 0x7E:
+			l.seq.Kind = EventESC
+			l.seq.Char = c
+			l.state = stateGround
+			emit(&l.seq)
+		default:
+			// not a sequence after all, the ESC is a character of its own
+			l.state = stateGround
+			emit(&Event{Kind: EventControl, Offset: l.seq.Offset, Char: 27})
+			l.ground(c, offset, emit)
+		}
+
+	case stateCSIParam, stateCSIIntermediate, stateCSIIgnore:
+		l.csi(c, offset, emit)
+	}
+}
+
+// ground handles a byte outside of any sequence
+func (l *lexer) ground(c byte, offset int64, emit func(*Event)) {
+	switch {
+	case c == 27:
+		l.seq = Event{Offset: offset}
+		l.count = 0
+		l.sub = 0
+		l.state = stateEscape
+	case c < 0x20:
+		emit(&Event{Kind: EventControl, Offset: offset, Char: c})
+	default:
+		emit(&Event{Kind: EventPrint, Offset: offset, Char: c})
+	}
+}
+
+// csi handles a byte of a control sequence
+func (l *lexer) csi(c byte, offset int64, emit func(*Event)) {
+	switch {
+	case c >= 0x40 && c <= 0x7E:
+		// final byte
+		ignore := l.state == stateCSIIgnore
+		l.state = stateGround
+
+		if !ignore {
+			l.seq.Kind = EventCSI
+			l.seq.Char = c
+			emit(&l.seq)
+		}
+
+	case c >= 0x30 && c <= 0x3F && l.state == stateCSIParam:
+		l.csiParam(c)
+
+	case c >= 0x20 && c <= 0x2F && l.state != stateCSIIgnore:
+		l.seq.Intermediates = append(l.seq.Intermediates, c)
+		l.state = stateCSIIntermediate
+
+	case c == 27:
+		// ESC starts over
+		l.state = stateGround
+		l.ground(c, offset, emit)
+
+	case c == 24 || c == 26:
+		// CAN and SUB cancel the sequence and do nothing else
+		l.state = stateGround
+
+	case c < 0x20:
+		// other controls take effect without ending the sequence
+		emit(&Event{Kind: EventControl, Offset: offset, Char: c})
+
+	case c >= 0x20 && c <= 0x3F:
+		// parameter bytes after intermediates, or a misplaced private marker
+		l.state = stateCSIIgnore
+
+	case c == 0x7F:
+		// DEL is ignored
+
+	default:
+		// not part of any sequence: drop the sequence, keep the byte
+		l.state = stateGround
+		l.ground(c, offset, emit)
+	}
+}
+
+// csiParam handles a parameter byte, 0x30-0x3F
+func (l *lexer) csiParam(c byte) {
+	switch {
+	case c >= '0' && c <= '9':
+		if l.count == 0 {
+			l.count = 1
+		}
+
+		i := l.count - 1
+		if i >= csiMaxParams {
+			return
+		}
+		for len(l.seq.Params) <= i {
+			l.seq.Params = append(l.seq.Params, 0)
+		}
+
+		// digits after a colon belong to the last sub-parameter
+		p := &l.seq.Params[i]
+		if l.sub > 0 {
+			sub := l.seq.Sub[i]
+			if l.sub > len(sub) {
+				return
+			}
+			p = &sub[l.sub-1]
+		}
+
+		v := *p*10 + int(c-'0')
+		if v > csiMaxParam {
+			v = csiMaxParam
+		}
+		*p = v
+
+	case c == ';':
+		if l.count == 0 {
+			l.count = 1
+		}
+		l.count++
+		l.sub = 0
+
+		for len(l.seq.Params) < l.count && len(l.seq.Params) < csiMaxParams {
+			l.seq.Params = append(l.seq.Params, 0)
+		}
+
+	case c == ':':
+		// a sub-parameter, kept with the parameter it follows
+		if l.count == 0 {
+			l.count = 1
+		}
+
+		i := l.count - 1
+		if i >= csiMaxParams {
+			return
+		}
+		for len(l.seq.Params) <= i {
+			l.seq.Params = append(l.seq.Params, 0)
+		}
+		for len(l.seq.Sub) <= i {
+			l.seq.Sub = append(l.seq.Sub, nil)
+		}
+
+		l.sub++
+		if len(l.seq.Sub[i]) < csiMaxParams {
+			l.seq.Sub[i] = append(l.seq.Sub[i], 0)
+		}
+
+	default:
+		// private markers are only valid as the first byte
+		if l.seq.Private == 0 && l.count == 0 {
+			l.seq.Private = c
+		} else {
+			l.state = stateCSIIgnore
+		}
+	}
+}
+
+// pending reports whether a sequence is cut off, e.g. at the end of the data
+func (l *lexer) pending() bool {
+	return l.state != stateGround
+}
+
+// Lexer splits a complete ANSi file into events, following the same rules as
+// the renderer. A SAUCE record at the end of the file is reported as a single
+// EventSauce rather than as characters.
+type Lexer struct {
+	data  []byte
+	size  int64 // bytes before the SAUCE record
+	sauce *Sauce
+	pos   int64
+	lex   lexer
+	queue []Event
+	done  bool
+}
+
+// NewLexer returns a Lexer reading data
+func NewLexer(data []byte) *Lexer {
+	l := &Lexer{data: data, size: int64(len(data))}
+
+	// a damaged record is left in the data as characters
+	record, err := readRecord(bytes.NewReader(data))
+	if err == nil && string(record.Sauceinf.ID[:]) == SauceID {
+		l.size = int64(sauceDataSize(data, record))
+		l.sauce = record
+	}
+
+	return l
+}
+
+// Next returns the next event. Once the data is used up it returns EventEOF,
+// preceded by EventSauce if the file has a SAUCE record. Sequences cut off by
+// the end of the data are dropped.
+func (l *Lexer) Next() Event {
+	for len(l.queue) == 0 && l.pos < l.size {
+		l.lex.feed(l.data[l.pos], l.push)
+		l.pos++
+	}
+
+	if len(l.queue) > 0 {
+		e := l.queue[0]
+		l.queue = l.queue[1:]
+		return e
+	}
+
+	if !l.done {
+		l.done = true
+		if l.sauce != nil {
+			return Event{Kind: EventSauce, Offset: l.size, Sauce: l.sauce}
+		}
+	}
+
+	return Event{Kind: EventEOF, Offset: int64(len(l.data))}
+}
+
+// push queues a copy of an event emitted by the lexer
+func (l *Lexer) push(e *Event) {
+	l.queue = append(l.queue, *e)
+}
@@ -0,0 +1,201 @@
+//  lexer_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// eventString describes an event in a few words, such as "CSI ?h 25" or
+// "Print a"
+func eventString(e Event) string {
+	switch e.Kind {
+	case EventPrint:
+		return "Print " + string([]byte{e.Char})
+	case EventControl:
+		return "Control " + strconv.Itoa(int(e.Char))
+	case EventESC:
+		return "ESC " + string(e.Intermediates) + string(e.Char)
+	case EventCSI:
+		s := "CSI " + string(e.Intermediates)
+		if e.Private != 0 {
+			s += string(e.Private)
+		}
+		s += string(e.Char)
+
+		var params []string
+		for i, p := range e.Params {
+			param := strconv.Itoa(p)
+			if i < len(e.Sub) {
+				for _, sub := range e.Sub[i] {
+					param += ":" + strconv.Itoa(sub)
+				}
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			s += " " + strings.Join(params, ";")
+		}
+		return s
+	}
+	return e.Kind.String()
+}
+
+// lex feeds data to a lexer and describes the events it emits
+func lex(data string) []string {
+	var l lexer
+	var events []string
+
+	for i := 0; i < len(data); i++ {
+		l.feed(data[i], func(e *Event) {
+			events = append(events, eventString(*e))
+		})
+	}
+	return events
+}
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		data   string
+		events []string
+	}{
+		{"a\r\n", []string{"Print a", "Control 13", "Control 10"}},
+		{"a\x1b[1;31mb", []string{"Print a", "CSI m 1;31", "Print b"}},
+		{"\x1b[m", []string{"CSI m"}},
+		{"\x1b[;5H", []string{"CSI H 0;5"}},
+		{"\x1b[?25h", []string{"CSI ?h 25"}},
+		{"\x1b[=7h", []string{"CSI =h 7"}},
+		{"\x1b[1 q", []string{"CSI  q 1"}},
+		{"\x1b[38:2::1:2:3m", []string{"CSI m 38:2:0:1:2:3"}},
+		{"\x1b(B", []string{"ESC (B"}},
+		{"\x1b7", []string{"ESC 7"}},
+		// large parameters are clamped
+		{"\x1b[99999999A", []string{"CSI A 65535"}},
+		// controls take effect in the middle of a sequence
+		{"\x1b[1\n2m", []string{"Control 10", "CSI m 12"}},
+		// CAN and SUB cancel a sequence, ESC starts a new one
+		{"\x1b[1\x18x", []string{"Print x"}},
+		{"\x1b[1\x1ax", []string{"Print x"}},
+		{"\x1b\x18x", []string{"Print x"}},
+		{"\x1b[1\x1b[2m", []string{"CSI m 2"}},
+		// malformed sequences are dropped up to their final byte
+		{"\x1b[1?mx", []string{"Print x"}},
+		{"\x1b[1 2mx", []string{"Print x"}},
+		// a DEL in a sequence is ignored
+		{"\x1b[1\x7fm", []string{"CSI m 1"}},
+		// an ESC that starts no sequence is a character of its own
+		{"\x1b\x80", []string{"Control 27", "Print \x80"}},
+		{"\x1b\n", []string{"Control 27", "Control 10"}},
+		// bytes that can't be part of a sequence end it
+		{"\x1b[1\x80", []string{"Print \x80"}},
+	}
+
+	for _, tt := range tests {
+		if events := lex(tt.data); !reflect.DeepEqual(events, tt.events) {
+			t.Errorf("%q: got %q, want %q", tt.data, events, tt.events)
+		}
+	}
+}
+
+func TestLexerLimits(t *testing.T) {
+	data := "\x1b[" + strings.Repeat("1;", 2*csiMaxParams) + "m"
+
+	var params []int
+	var l lexer
+	for i := 0; i < len(data); i++ {
+		l.feed(data[i], func(e *Event) {
+			params = e.Params
+		})
+	}
+
+	if len(params) != csiMaxParams {
+		t.Errorf("got %d parameters, want %d", len(params), csiMaxParams)
+	}
+
+	data = "\x1b[38" + strings.Repeat(":1", 2*csiMaxParams) + "m"
+	if events := lex(data); len(events) != 1 || strings.Count(events[0], ":") != csiMaxParams {
+		t.Errorf("%s: got %q, want %d sub-parameters", data, events, csiMaxParams)
+	}
+
+	if events := lex(fmt.Sprintf("\x1b[%dA", csiMaxParam+1)); len(events) != 1 || events[0] != "CSI A 65535" {
+		t.Errorf("got %q, want the parameter clamped", events)
+	}
+}
+
+// lexAll reads every event of data with a Lexer
+func lexAll(data []byte) []Event {
+	l := NewLexer(data)
+
+	var events []Event
+	for {
+		e := l.Next()
+		events = append(events, e)
+		if e.Kind == EventEOF {
+			return events
+		}
+	}
+}
+
+func TestLexerOffsets(t *testing.T) {
+	events := lexAll([]byte("a\x1b[1mb\x1b[2"))
+
+	want := []struct {
+		kind   EventKind
+		offset int64
+	}{
+		{EventPrint, 0},
+		{EventCSI, 1},
+		{EventPrint, 5},
+		// the cut off sequence is dropped
+		{EventEOF, 9},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Kind != want[i].kind || e.Offset != want[i].offset {
+			t.Errorf("event %d: got %v at %d, want %v at %d", i, e.Kind, e.Offset, want[i].kind, want[i].offset)
+		}
+	}
+}
+
+func TestLexerSauce(t *testing.T) {
+	data := withSauce(t, "ab", FileANSi)
+
+	events := lexAll(data)
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+
+	sauce := events[2]
+	if sauce.Kind != EventSauce || sauce.Offset != 2 || sauce.Sauce == nil || sauce.Sauce.Sauceinf.Type() != FileANSi {
+		t.Errorf("got %+v, want the SAUCE record at offset 2", sauce)
+	}
+	if eof := events[3]; eof.Kind != EventEOF || eof.Offset != int64(len(data)) {
+		t.Errorf("got %v at %d, want EOF at %d", eof.Kind, eof.Offset, len(data))
+	}
+
+	// a record claiming more comments than fit is left in the data
+	data[len(data)-recordSize+104] = 200
+	events = lexAll(data)
+	for _, e := range events {
+		if e.Kind == EventSauce {
+			t.Errorf("damaged record reported as %+v", e)
+		}
+	}
+	if len(events) != len(data)+1 {
+		t.Errorf("got %d events, want a character for every byte and EOF", len(events))
+	}
+}
@@ -0,0 +1,191 @@
+//  page.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+// ansiMaxRows limits how far down the cursor of an ANSi file can draw, wide
+// pages stop earlier to stay within maxCells
+const ansiMaxRows = 0xFFFF
+
+// page is the sheet an ANSi file draws on: a fixed number of columns and as
+// many rows as the cursor reaches, or a terminal screen of a fixed height.
+// Positions outside of it are ignored.
+type page struct {
+	width   int
+	height  int  // 0 for as many rows as needed
+	maxRows int  // the most rows the page can have
+	blank   Cell // what rows that were never drawn on hold
+	rows    [][]Cell
+}
+
+func newPage(width int, blank Cell) *page {
+	maxRows := ansiMaxRows
+	if width > 0 && maxCells/width < maxRows {
+		maxRows = maxCells / width
+	}
+
+	return &page{width: width, maxRows: maxRows, blank: blank}
+}
+
+// newScreen returns a page of a fixed height
+func newScreen(width, height int, blank Cell) *page {
+	p := &page{width: width, height: height, maxRows: height, blank: blank}
+	p.clear()
+	return p
+}
+
+// row returns row y, adding blank rows up to it if needed, or nil if y is
+// outside the page
+func (p *page) row(y int) []Cell {
+	if y < 0 || y >= p.maxRows {
+		return nil
+	}
+
+	for len(p.rows) <= y {
+		p.rows = append(p.rows, p.blankRow())
+	}
+
+	return p.rows[y]
+}
+
+func (p *page) blankRow() []Cell {
+	return p.filledRow(p.blank)
+}
+
+func (p *page) filledRow(cell Cell) []Cell {
+	r := make([]Cell, p.width)
+	for i := range r {
+		r[i] = cell
+	}
+	return r
+}
+
+// set writes cell at column x of row y
+func (p *page) set(x, y int, cell Cell) {
+	if x < 0 || x >= p.width {
+		return
+	}
+
+	if r := p.row(y); r != nil {
+		r[x] = cell
+	}
+}
+
+// erase fills columns x0 up to but not including x1 of row y with cell
+func (p *page) erase(x0, x1, y int, cell Cell) {
+	if x0 < 0 {
+		x0 = 0
+	}
+	if x1 > p.width {
+		x1 = p.width
+	}
+	if x0 >= x1 {
+		return
+	}
+
+	if r := p.row(y); r != nil {
+		for x := x0; x < x1; x++ {
+			r[x] = cell
+		}
+	}
+}
+
+// eraseRows fills rows y0 up to but not including y1 with cell, rows past
+// the ones drawn on so far are left alone
+func (p *page) eraseRows(y0, y1 int, cell Cell) {
+	if y0 < 0 {
+		y0 = 0
+	}
+
+	for y := y0; y < y1 && y < len(p.rows); y++ {
+		p.erase(0, p.width, y, cell)
+	}
+}
+
+// insertChars shifts the cells from column x of row y right by n, the ones
+// pushed past the last column are lost and the gap is filled with cell
+func (p *page) insertChars(x, y, n int, cell Cell) {
+	r := p.row(y)
+	if r == nil || x < 0 || x >= p.width || n < 1 {
+		return
+	}
+
+	if n < p.width-x {
+		copy(r[x+n:], r[x:])
+	}
+	p.erase(x, x+n, y, cell)
+}
+
+// deleteChars removes n cells from column x of row y, shifting the rest of
+// the row left and filling the end with cell
+func (p *page) deleteChars(x, y, n int, cell Cell) {
+	r := p.row(y)
+	if r == nil || x < 0 || x >= p.width || n < 1 {
+		return
+	}
+
+	if n > p.width-x {
+		n = p.width - x
+	}
+	copy(r[x:], r[x+n:])
+	p.erase(p.width-n, p.width, y, cell)
+}
+
+// insertRows pushes the rows from y down by n ones filled with cell and
+// returns how many it inserted. Rows pushed off the bottom of a screen are
+// lost, a page only takes as many rows as it has room for.
+func (p *page) insertRows(y, n int, cell Cell) int {
+	if y < 0 || y >= len(p.rows) || n < 1 {
+		return 0
+	}
+	if p.height > 0 {
+		n = min(n, len(p.rows)-y)
+	} else {
+		n = min(n, p.maxRows-len(p.rows))
+	}
+
+	inserted := make([][]Cell, n)
+	for i := range inserted {
+		inserted[i] = p.filledRow(cell)
+	}
+
+	p.rows = append(p.rows[:y], append(inserted, p.rows[y:]...)...)
+
+	if len(p.rows) > p.maxRows {
+		p.rows = p.rows[:p.maxRows]
+	}
+	return n
+}
+
+// deleteRows removes n rows from y, pulling the ones below up. A screen
+// gets rows filled with cell at the bottom in their place.
+func (p *page) deleteRows(y, n int, cell Cell) {
+	if y < 0 || y >= len(p.rows) || n < 1 {
+		return
+	}
+
+	if n > len(p.rows)-y {
+		n = len(p.rows) - y
+	}
+
+	p.rows = append(p.rows[:y], p.rows[y+n:]...)
+
+	for len(p.rows) < p.height {
+		p.rows = append(p.rows, p.filledRow(cell))
+	}
+}
+
+// clear drops everything drawn so far, a screen is filled with blank rows
+func (p *page) clear() {
+	p.rows = nil
+
+	for len(p.rows) < p.height {
+		p.rows = append(p.rows, p.blankRow())
+	}
+}
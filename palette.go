@@ -0,0 +1,152 @@
+//  palette.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import "image/color"
+
+// Palette maps the color indexes of a Canvas to RGB values. Indexes follow
+// the order of the VGA text mode attribute byte: black, blue, green, cyan,
+// red, magenta, brown, light gray and then their bright versions.
+type Palette []color.RGBA
+
+// standard VGA text mode palette
+var vgaPalette = Palette{
+	{0, 0, 0, 255},
+	{0, 0, 170, 255},
+	{0, 170, 0, 255},
+	{0, 170, 170, 255},
+	{170, 0, 0, 255},
+	{170, 0, 170, 255},
+	{170, 85, 0, 255},
+	{170, 170, 170, 255},
+	{85, 85, 85, 255},
+	{85, 85, 255, 255},
+	{85, 255, 85, 255},
+	{85, 255, 255, 255},
+	{255, 85, 85, 255},
+	{255, 85, 255, 255},
+	{255, 255, 85, 255},
+	{255, 255, 255, 255},
+}
+
+// Amiga Workbench palette, used by the workbench rendering mode
+var workbenchPalette = Palette{
+	{170, 170, 170, 255},
+	{0, 0, 255, 255},
+	{255, 255, 255, 255},
+	{0, 255, 255, 255},
+	{0, 0, 0, 255},
+	{255, 0, 255, 255},
+	{102, 136, 187, 255},
+	{255, 255, 255, 255},
+	{170, 170, 170, 255},
+	{0, 0, 255, 255},
+	{255, 255, 255, 255},
+	{0, 255, 255, 255},
+	{0, 0, 0, 255},
+	{255, 0, 255, 255},
+	{102, 136, 187, 255},
+	{255, 255, 255, 255},
+}
+
+// ansiToVGA maps the ANSi color numbers of SGR 30-37 and 40-47 to palette indexes
+var ansiToVGA = [8]int{0, 4, 2, 6, 1, 5, 3, 7}
+
+// XtermPalette returns the 256 color palette used by SGR 38;5 and 48;5, in
+// xterm order: the 16 ANSi colors (black, red, green, yellow, blue, magenta,
+// cyan, white, then bright), a 6x6x6 color cube and a 24 step gray ramp. The
+// ANSi colors are the VGA ones so that 38;5;1 matches SGR 31.
+func XtermPalette() Palette {
+	p := make(Palette, 256)
+
+	for i := 0; i < 16; i++ {
+		p[i] = vgaPalette[ansiColor(i)]
+	}
+
+	levels := [6]uint8{0, 95, 135, 175, 215, 255}
+	for i := 0; i < 216; i++ {
+		p[16+i] = color.RGBA{levels[i/36], levels[i/6%6], levels[i%6], 255}
+	}
+
+	for i := 0; i < 24; i++ {
+		gray := uint8(8 + i*10)
+		p[232+i] = color.RGBA{gray, gray, gray, 255}
+	}
+
+	return p
+}
+
+// ansiPalette builds the palette of an ANSi canvas from a palette in xterm
+// order: the ANSi colors move to their VGA indexes, or are replaced by base
+// in modes with their own colors, the other 240 stay where they are
+func ansiPalette(xterm Palette, base Palette) Palette {
+	colors := XtermPalette()
+
+	// a short palette only overrides the first colors
+	copy(colors, xterm)
+
+	p := colors.clone()
+	for i := 0; i < 16; i++ {
+		p[ansiColor(i)] = colors[i]
+	}
+
+	copy(p, base)
+
+	return p
+}
+
+// vgaPaletteBytes returns count palette entries as 6-bit VGA DAC values,
+// the way readVGAPalette reads them. Missing entries are black.
+func vgaPaletteBytes(p Palette, count int) []byte {
+	data := make([]byte, count*3)
+
+	for i := 0; i < count && i < len(p); i++ {
+		data[i*3] = p[i].R >> 2
+		data[i*3+1] = p[i].G >> 2
+		data[i*3+2] = p[i].B >> 2
+	}
+
+	return data
+}
+
+// clone returns a copy of p that can be modified without affecting p
+func (p Palette) clone() Palette {
+	return append(Palette(nil), p...)
+}
+
+// nearest returns the entry from up to but not including to that is closest
+// to rgba
+func (p Palette) nearest(rgba color.RGBA, from, to int) int {
+	best, bestDistance := from, -1
+
+	for i := from; i < to && i < len(p); i++ {
+		dr := int(rgba.R) - int(p[i].R)
+		dg := int(rgba.G) - int(p[i].G)
+		db := int(rgba.B) - int(p[i].B)
+
+		if d := dr*dr + dg*dg + db*db; bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+
+	return best
+}
+
+// readVGAPalette reads count 6-bit RGB triplets from data
+func readVGAPalette(data []byte, count int) Palette {
+	p := make(Palette, count)
+
+	for i := range p {
+		r, g, b := data[i*3], data[i*3+1], data[i*3+2]
+		p[i] = color.RGBA{r<<2 | r>>4, g<<2 | g>>4, b<<2 | b>>4, 255}
+	}
+
+	return p
+}
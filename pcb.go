@@ -15,50 +15,56 @@
 
 package goansi
 
-import (
-	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"os"
-)
-
-// Character structure
-type pcbChar struct {
-	positionX       int
-	positionY       int
-	colorBackground int
-	colorForeground int
-	currentChar     int
+import "context"
+
+// pcbHexDigit converts a PCBoard color digit, ok is false for non-hex digits
+func pcbHexDigit(c byte) (value int, ok bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10, true
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10, true
+	}
+	return 0, false
 }
 
-func pcboard(inputFileBuffer []byte, inputFileSize int64, fontName string, bits int, icecolors bool) image.Image {
+func pcboard(ctx context.Context, inputFileBuffer []byte, inputFileSize int64, fontName string, bits int, icecolors bool) (*Canvas, error) {
 	// some type declarations
-	var f font
+	var f Font
 	columns := 80
 	var loop int
 
 	// font selection
 	alSelectFont(&f, fontName)
 
-	// libgd image pointers
-	var imPCB draw.Image
-
 	// process PCBoard
-	var char, currentChar, nextChar int
+	var currentChar, nextChar int
 	var colorBackground, colorForeground int = 0, 7
+	var colorAttr Attr
 	var posX, posY, posXMax, posYMax int
 
-	// PCB buffer structure array definition
-	var pcbBuffer []pcbChar
+	// characters written so far, the canvas size isn't known until the end
+	var pcbBuffer []cellWrite
+
+	// characters below the rows a canvas can hold are dropped
+	maxRows := maxCells / columns
 
 	// reset loop
 	loop = 0
-	structIndex := 0
+
+	// peek returns the byte at index i, or 0 past the end of the data
+	peek := func(i int) byte {
+		if i < int(inputFileSize) {
+			return inputFileBuffer[i]
+		}
+		return 0
+	}
 
 	for loop < int(inputFileSize) {
 		currentChar = int(inputFileBuffer[loop])
-		nextChar = int(inputFileBuffer[loop+1])
+		nextChar = int(peek(loop + 1))
 
 		if posX == 80 {
 			posY++
@@ -88,26 +94,21 @@ func pcboard(inputFileBuffer []byte, inputFileSize int64, fontName string, bits
 			break
 		}
 
-		// PCB sequence
-		if currentChar == 64 && nextChar == 88 {
-			colorBackground = int(inputFileBuffer[loop+2])
-			if colorBackground >= 65 {
-				colorBackground -= 55
-			} else {
-				colorBackground -= 48
-			}
+		// PCB sequence, anything but two hex digits after @X is plain text
+		background, bgOk := pcbHexDigit(peek(loop + 2))
+		foreground, fgOk := pcbHexDigit(peek(loop + 3))
+
+		if currentChar == 64 && nextChar == 88 && bgOk && fgOk {
+			colorBackground = background
+			colorAttr = 0
 			if !icecolors && colorBackground > 7 {
 				colorBackground -= 8
+				colorAttr = AttrBlink
 			}
-			colorForeground = int(inputFileBuffer[loop+3])
-			if colorForeground >= 65 {
-				colorForeground -= 55
-			} else {
-				colorForeground -= 48
-			}
+			colorForeground = foreground
 			loop += 3
 		} else if currentChar == 64 && nextChar == 67 &&
-			inputFileBuffer[loop+2] == 'L' && inputFileBuffer[loop+3] == 'S' {
+			peek(loop+2) == 'L' && peek(loop+3) == 'S' {
 			// erase display
 			posX = 0
 			posY = 0
@@ -116,16 +117,16 @@ func pcboard(inputFileBuffer []byte, inputFileSize int64, fontName string, bits
 			posYMax = 0
 
 			loop += 4
-		} else if currentChar == 64 && nextChar == 80 && inputFileBuffer[loop+2] == 'O' && inputFileBuffer[loop+3] == 'S' && inputFileBuffer[loop+4] == ':' {
+		} else if currentChar == 64 && nextChar == 80 && peek(loop+2) == 'O' && peek(loop+3) == 'S' && peek(loop+4) == ':' {
 			// cursor position
-			if inputFileBuffer[loop+6] == '@' {
-				posX = int(((inputFileBuffer[loop+5]) - 48)) - 1
+			if peek(loop+6) == '@' {
+				posX = int(peek(loop+5)) - 48 - 1
 				loop += 5
 			} else {
-				posX = int((10*((inputFileBuffer[loop+5])-48) + (inputFileBuffer[loop+6]) - 48)) - 1
+				posX = 10*(int(peek(loop+5))-48) + int(peek(loop+6)) - 48 - 1
 				loop += 6
 			}
-		} else if currentChar != 10 && currentChar != 13 && currentChar != 9 {
+		} else if currentChar != 10 && currentChar != 13 && currentChar != 9 && posY < maxRows {
 			// record number of columns and lines used
 			if posX > posXMax {
 				posXMax = posX
@@ -135,18 +136,11 @@ func pcboard(inputFileBuffer []byte, inputFileSize int64, fontName string, bits
 				posYMax = posY
 			}
 
-			var newChar pcbChar
-
-			// write current character in pcbChar structure
-			newChar.positionX = posX
-			newChar.positionY = posY
-			newChar.colorBackground = colorBackground
-			newChar.colorForeground = colorForeground
-			newChar.currentChar = currentChar
+			// write current character in the pcb buffer
+			newChar := Cell{Char: byte(currentChar), Fg: colorForeground, Bg: colorBackground, Attr: colorAttr}
 
-			pcbBuffer = append(pcbBuffer, newChar)
+			pcbBuffer = append(pcbBuffer, cellWrite{posX, posY, newChar})
 
-			structIndex++
 			posX++
 		}
 		loop++
@@ -154,47 +148,17 @@ func pcboard(inputFileBuffer []byte, inputFileSize int64, fontName string, bits
 	posXMax++
 	posYMax++
 
-	// allocate color palette
-	var colors [16]color.RGBA
-
-	colors[0] = color.RGBA{0, 0, 0, 255}
-	colors[1] = color.RGBA{0, 0, 170, 255}
-	colors[2] = color.RGBA{0, 170, 0, 255}
-	colors[3] = color.RGBA{0, 170, 170, 255}
-	colors[4] = color.RGBA{170, 0, 0, 255}
-	colors[5] = color.RGBA{170, 0, 170, 255}
-	colors[6] = color.RGBA{170, 85, 0, 255}
-	colors[7] = color.RGBA{170, 170, 170, 255}
-	colors[8] = color.RGBA{85, 85, 85, 255}
-	colors[9] = color.RGBA{85, 85, 255, 255}
-	colors[10] = color.RGBA{85, 255, 85, 255}
-	colors[11] = color.RGBA{85, 255, 255, 255}
-	colors[12] = color.RGBA{255, 85, 85, 255}
-	colors[13] = color.RGBA{255, 85, 255, 255}
-	colors[14] = color.RGBA{255, 255, 85, 255}
-	colors[15] = color.RGBA{255, 255, 255, 255}
-
-	imPCB = image.NewRGBA(image.Rect(0, 0, columns*bits, posYMax*f.sizeY))
-
-	if imPCB == nil {
-		fmt.Printf("\nError, can't allocate buffer image memory.\n\n")
-		os.Exit(6)
-	}
-
-	black := color.RGBA{0, 0, 0, 255}
-	draw.Draw(imPCB, imPCB.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
+	canvas := NewCanvas(columns, posYMax, &f, vgaPalette.clone())
+	canvas.Bits = bits
 
 	// render PCB
-	for loop = 0; loop < structIndex; loop++ {
-		// grab our chars out of the structure
-		posX = pcbBuffer[loop].positionX
-		posY = pcbBuffer[loop].positionY
-		colorBackground = pcbBuffer[loop].colorBackground
-		colorForeground = pcbBuffer[loop].colorForeground
-		char = pcbBuffer[loop].currentChar
-
-		alDrawChar(imPCB, f.data, bits, f.sizeY, posX, posY, colors[colorBackground], colors[colorForeground], byte(char))
+	for _, w := range pcbBuffer {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		canvas.Set(w.x, w.y, w.cell)
 	}
 
-	return imPCB
+	return canvas, nil
 }
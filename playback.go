@@ -0,0 +1,146 @@
+//  playback.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// PlaybackOptions controls how Playback animates a file. The zero value
+// plays at 14400 baud on an 80x25 screen, capturing 10 frames per second.
+type PlaybackOptions struct {
+	TerminalOptions               // the screen the file is played on
+	Format          Format        // file format, FormatAuto picks one from the data
+	FileName        string        // name of the input, its extension is a hint for FormatAuto
+	Baud            int           // modem speed in bits per second (default: 14400)
+	FrameRate       int           // frames captured per second (default: 10)
+	Cursor          bool          // draw the cursor
+	Hold            time.Duration // extra time the last frame is shown
+
+	// UseSauceHints takes the width, iCE colors, letter spacing and font of
+	// the SAUCE record for the options left unset, except for SkipHints
+	UseSauceHints bool
+	SkipHints     SauceHint
+}
+
+// Playback plays a complete ANSi file from r on a Terminal as fast as a modem
+// would have received it, and captures the screen as an animation. Each
+// byte takes 10 bits on the line, 8 data bits plus start and stop bits.
+// Files of other formats aren't sent as they are and fail with ErrNotANSI.
+func Playback(ctx context.Context, r io.Reader, opts PlaybackOptions) (*Animation, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Baud == 0 {
+		opts.Baud = 14400
+	}
+	if opts.FrameRate == 0 {
+		opts.FrameRate = 10
+	}
+	if opts.Baud < 0 || opts.FrameRate < 0 || opts.Hold < 0 {
+		return nil, &OpError{Op: "playback", Err: ErrBadOption}
+	}
+
+	// the SAUCE record and anything after the EOF character isn't sent
+	record, err := readRecord(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	data = StripSauce(data, record)
+
+	format := opts.Format
+	if format == FormatAuto {
+		format = detectFormat(data, record, filepath.Ext(opts.FileName))
+	}
+	if format != FormatANSI && format != FormatDIZ {
+		return nil, &FormatError{Format: format.String(), Err: ErrNotANSI}
+	}
+
+	if opts.UseSauceHints && string(record.Sauceinf.ID[:]) == SauceID {
+		hinted := RenderOptions{
+			Font:      opts.Font,
+			Bits:      opts.Bits,
+			Columns:   opts.Width,
+			IceColors: opts.IceColors,
+			SkipHints: opts.SkipHints,
+		}
+		sauceHints(&hinted, &opts.Width, &record.Sauceinf, format)
+
+		opts.Font = hinted.Font
+		opts.Bits = hinted.Bits
+		opts.IceColors = hinted.IceColors
+	}
+
+	if i := bytes.IndexByte(data, 26); i >= 0 {
+		data = data[:i]
+	}
+
+	term := NewTerminal(opts.TerminalOptions)
+
+	frameDelay := time.Second / time.Duration(opts.FrameRate)
+	bytesPerFrame := float64(opts.Baud) / 10 / float64(opts.FrameRate)
+
+	anim := &Animation{}
+	var prev *image.RGBA
+	sent := 0.0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		im := term.playbackImage(opts.Cursor)
+		if prev == nil {
+			anim.Width, anim.Height = im.Bounds().Dx(), im.Bounds().Dy()
+		}
+		anim.add(im, prev, frameDelay)
+		prev = im
+
+		if int(sent) >= len(data) {
+			break
+		}
+
+		next := sent + bytesPerFrame
+		term.Write(data[int(sent):min(int(next), len(data))])
+		sent = next
+	}
+
+	anim.Frames[len(anim.Frames)-1].Delay += opts.Hold
+
+	return anim, nil
+}
+
+// playbackImage draws the screen, with the cursor as an underline in light
+// gray if cursor is set
+func (t *Terminal) playbackImage(cursor bool) *image.RGBA {
+	canvas := t.Canvas()
+	im, _ := canvas.Draw(context.Background())
+
+	if cursor {
+		x, y := t.Cursor()
+		bits := canvas.Bits
+		height := canvas.Font.Height
+
+		// the bottom two scanlines of the cell, like the VGA text mode cursor
+		r := image.Rect(x*bits, (y+1)*height-2, (x+1)*bits, (y+1)*height)
+		draw.Draw(im, r, &image.Uniform{canvas.paletteColor(7)}, image.ZP, draw.Src)
+	}
+
+	return im
+}
@@ -0,0 +1,98 @@
+//  playback_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPlaybackFormats(t *testing.T) {
+	xb := []byte(xbinID + "\x01\x00\x01\x00\x10\x00A\x07")
+	bin := bytes.Repeat([]byte{0xDB, 0x1E}, 80)
+
+	tests := []struct {
+		name string
+		data []byte
+		opts PlaybackOptions
+		ok   bool
+	}{
+		{"ansi", []byte("\x1b[1mhello"), PlaybackOptions{}, true},
+		{"diz", []byte("hello"), PlaybackOptions{FileName: "file_id.diz"}, true},
+		{"xbin", xb, PlaybackOptions{}, false},
+		{"xbin forced to ansi", xb, PlaybackOptions{Format: FormatANSI}, true},
+		{"binary", bin, PlaybackOptions{}, false},
+		{"binary by extension", []byte("hello"), PlaybackOptions{FileName: "x.bin"}, false},
+		{"ansi forced to binary", []byte("\x1b[1mhello"), PlaybackOptions{Format: FormatBinary}, false},
+	}
+
+	for _, tt := range tests {
+		_, err := Playback(context.Background(), bytes.NewReader(tt.data), tt.opts)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrNotANSI) {
+			t.Errorf("%s: got %v, want ErrNotANSI", tt.name, err)
+		}
+	}
+}
+
+func TestPlaybackSauceHints(t *testing.T) {
+	var record Sauce
+	record.Sauceinf.SetType(FileANSi)
+	record.Sauceinf.Tinfo1 = 40
+	record.Sauceinf.Flags = byte(FlagLetter9)
+
+	var buf bytes.Buffer
+	buf.WriteString("hello")
+	if err := WriteSauce(&buf, record); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts  PlaybackOptions
+		width int
+	}{
+		{PlaybackOptions{}, 80 * 8},
+		{PlaybackOptions{UseSauceHints: true}, 40 * 9},
+		// options that are set win over the record
+		{PlaybackOptions{UseSauceHints: true, TerminalOptions: TerminalOptions{Width: 20, Bits: 8}}, 20 * 8},
+		// even when they are zero
+		{PlaybackOptions{UseSauceHints: true, SkipHints: HintColumns | HintBits}, 80 * 8},
+	}
+
+	for _, tt := range tests {
+		anim, err := Playback(context.Background(), bytes.NewReader(buf.Bytes()), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if anim.Width != tt.width {
+			t.Errorf("%+v: got %d pixels wide, want %d", tt.opts, anim.Width, tt.width)
+		}
+	}
+}
+
+func TestTerminalModes(t *testing.T) {
+	ced := NewTerminal(TerminalOptions{Width: 4, Height: 1, Mode: "ced"}).Canvas()
+	if cell := ced.At(0, 0); cell.Fg != 0 || cell.Bg != 7 {
+		t.Errorf("ced: got %+v, want black on gray", cell)
+	}
+
+	workbench := NewTerminal(TerminalOptions{Mode: "workbench"}).Canvas()
+	if workbench.Palette[0] != workbenchPalette[0] || workbench.Palette[7] != workbenchPalette[7] {
+		t.Error("workbench: got the default palette")
+	}
+
+	if !NewTerminal(TerminalOptions{Mode: "transparent"}).Canvas().Transparent {
+		t.Error("transparent: got an opaque canvas")
+	}
+}
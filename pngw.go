@@ -18,14 +18,13 @@ package goansi
 import (
 	"image"
 	"image/png"
-	"log"
 	"os"
 
 	"github.com/nfnt/resize"
 )
 
 // WritePng takes an image and filename and encodes to png
-func WritePng(fileName string, img image.Image, scaleFactor float32) {
+func WritePng(fileName string, img image.Image, scaleFactor float32) error {
 
 	scaledImg := img
 
@@ -38,15 +37,13 @@ func WritePng(fileName string, img image.Image, scaleFactor float32) {
 	// create output file
 	f, err := os.Create(fileName)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if err := png.Encode(f, scaledImg); err != nil {
 		f.Close()
-		log.Fatal(err)
+		return err
 	}
 
-	if err := f.Close(); err != nil {
-		log.Fatal(err)
-	}
+	return f.Close()
 }
@@ -0,0 +1,128 @@
+//  preview.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+)
+
+// EncodePreview prints im for a terminal with upper half block characters,
+// each showing two pixels on top of each other, so that an image can be
+// checked without an image viewer. It is scaled down to at most width
+// columns, every pixel of the preview the average of the ones it covers.
+// Mostly transparent pixels keep the background of the terminal.
+func EncodePreview(w io.Writer, im image.Image, width int, mode ColorMode) error {
+	if width < 1 {
+		return &OpError{Op: "preview", Err: ErrBadOption}
+	}
+
+	pixels := previewPixels(im, width)
+
+	enc := newUTF8Encoder(mode)
+
+	bw := bufio.NewWriter(w)
+
+	for y := 0; y < len(pixels); y += 2 {
+		var prev cellStyle
+
+		for x, top := range pixels[y] {
+			var bottom color.RGBA
+			if y+1 < len(pixels) {
+				bottom = pixels[y+1][x]
+			}
+
+			// the lower half block puts a transparent pixel on top
+			char := '▀'
+			style := cellStyle{fg: top, bg: bottom}
+			if top.A == 0 {
+				char = '▄'
+				style = cellStyle{fg: bottom}
+			}
+			if style.fg.A == 0 {
+				char = ' '
+			}
+
+			if x == 0 || style != prev {
+				bw.WriteString(enc.sgr(style))
+				prev = style
+			}
+
+			bw.WriteRune(char)
+		}
+
+		bw.WriteString("\x1b[0m\n")
+	}
+
+	return bw.Flush()
+}
+
+// previewPixels scales im down to width pixels across, keeping its aspect
+// ratio, and returns the rows of pixels. They are either opaque or
+// transparent.
+func previewPixels(im image.Image, width int) [][]color.RGBA {
+	b := im.Bounds()
+	if b.Empty() {
+		return nil
+	}
+
+	if width > b.Dx() {
+		width = b.Dx()
+	}
+
+	scale := float64(b.Dx()) / float64(width)
+	height := int(float64(b.Dy())/scale + 0.5)
+	if height < 1 {
+		height = 1
+	}
+
+	// the source pixels from n*scale up to (n+1)*scale
+	span := func(n, limit int) (int, int) {
+		from := int(float64(n) * scale)
+		to := int(float64(n+1) * scale)
+		if to > limit {
+			to = limit
+		}
+		if to <= from {
+			to = from + 1
+		}
+		return from, to
+	}
+
+	pixels := make([][]color.RGBA, height)
+
+	for py := range pixels {
+		pixels[py] = make([]color.RGBA, width)
+		y0, y1 := span(py, b.Dy())
+
+		for px := range pixels[py] {
+			x0, x1 := span(px, b.Dx())
+
+			var r, g, bl, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := im.At(b.Min.X+x, b.Min.Y+y).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// colors are premultiplied, dividing by alpha takes it out again
+			if a/n < 0x8000 {
+				continue
+			}
+			pixels[py][px] = color.RGBA{uint8(r * 0xFF / a), uint8(g * 0xFF / a), uint8(bl * 0xFF / a), 0xFF}
+		}
+	}
+
+	return pixels
+}
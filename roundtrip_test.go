@@ -0,0 +1,184 @@
+//  roundtrip_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// roundTrip is a writer along with how its files are read back and what
+// they can hold
+type roundTrip struct {
+	name   string
+	encode func(*Canvas, io.Writer) error
+	opts   func(*Canvas) RenderOptions // how the written file is read
+	fits   func(*Canvas) bool          // whether the format can hold the canvas
+	colors int                         // palette colors the format holds, 0 for 24-bit
+	blink  bool                        // whether blinking text is kept
+	width  int                         // the width files are read back with, 0 for the canvas width
+}
+
+var roundTrips = []roundTrip{
+	{
+		name:   "BIN",
+		encode: (*Canvas).EncodeBin,
+		opts: func(c *Canvas) RenderOptions {
+			return RenderOptions{Format: FormatBinary, Columns: c.Width, IceColors: !c.blinks()}
+		},
+		fits:   func(c *Canvas) bool { return true },
+		colors: 16,
+		blink:  true,
+	},
+	{
+		name:   "ADF",
+		encode: (*Canvas).EncodeADF,
+		opts:   func(c *Canvas) RenderOptions { return RenderOptions{Format: FormatArtworx} },
+		fits:   func(c *Canvas) bool { return c.Width <= 80 && c.Font.Height == 16 },
+		colors: 16,
+		width:  80,
+	},
+	{
+		name:   "IDF",
+		encode: (*Canvas).EncodeIDF,
+		opts:   func(c *Canvas) RenderOptions { return RenderOptions{Format: FormatIceDraw} },
+		fits:   func(c *Canvas) bool { return c.Font.Height == 16 },
+		colors: 16,
+	},
+	{
+		name:   "Tundra",
+		encode: (*Canvas).EncodeTundra,
+		opts: func(c *Canvas) RenderOptions {
+			return RenderOptions{Format: FormatTundra, Columns: c.Width}
+		},
+		fits: func(c *Canvas) bool { return true },
+	},
+	{
+		name:   "XBin",
+		encode: (*Canvas).EncodeXBin,
+		opts:   func(c *Canvas) RenderOptions { return RenderOptions{Format: FormatXBin} },
+		fits:   func(c *Canvas) bool { return true },
+		colors: 16,
+		blink:  true,
+	},
+}
+
+// glyphBlank tells whether a character shows no foreground in PC fonts
+func glyphBlank(char byte) bool {
+	return char == ' ' || char == 0 || char == 255
+}
+
+// wantColors returns the colors a cell of c should have once written as rt
+// and read back as got: the nearest of the colors the format holds, with
+// dark backgrounds if blink takes the bit of the bright ones
+func (rt roundTrip) wantColors(c, got *Canvas, cell Cell, darkBg bool) (fg, bg color.RGBA) {
+	fg, bg = c.CellColors(cell)
+	fg.A, bg.A = 255, 255
+	if rt.colors == 0 {
+		return fg, bg
+	}
+
+	p := got.Palette[:rt.colors]
+	fgIndex, bgIndex := p.nearest(fg, 0, rt.colors), p.nearest(bg, 0, rt.colors)
+	if darkBg {
+		bgIndex &= 7
+	}
+	return p[fgIndex], p[bgIndex]
+}
+
+func TestRoundTripExamples(t *testing.T) {
+	files, err := filepath.Glob("examples/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skip("no examples")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := decodeData(t, data, RenderOptions{FileName: file})
+
+		for _, rt := range roundTrips {
+			name := filepath.Base(file) + " as " + rt.name
+
+			var buf bytes.Buffer
+			err := rt.encode(c, &buf)
+			if !rt.fits(c) {
+				if err == nil {
+					t.Errorf("%s: a %dx%d canvas with a %d pixel font was written", name, c.Width, c.Height, c.Font.Height)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+
+			got := decodeData(t, buf.Bytes(), rt.opts(c))
+			rt.compare(t, name, c, got)
+		}
+	}
+}
+
+// compare checks got, read back from a file written as rt, against c cell
+// by cell
+func (rt roundTrip) compare(t *testing.T, name string, c, got *Canvas) {
+	t.Helper()
+
+	width := rt.width
+	if width == 0 {
+		width = c.Width
+	}
+	if got.Width != width || got.Height != c.Height {
+		t.Errorf("%s: got %dx%d, want %dx%d", name, got.Width, got.Height, width, c.Height)
+		return
+	}
+
+	darkBg := rt.blink && c.blinks()
+
+	errs := 0
+	for y := 0; y < c.Height && errs < 5; y++ {
+		for x := 0; x < c.Width && errs < 5; x++ {
+			cell, gotCell := c.At(x, y), got.At(x, y)
+
+			wantFg, wantBg := rt.wantColors(c, got, cell, darkBg)
+			gotFg, gotBg := got.CellColors(gotCell)
+			gotFg.A, gotBg.A = 255, 255
+
+			blinks := cell.Attr&AttrBlink != 0 && rt.blink
+			ok := gotCell.Char == cell.Char && gotBg == wantBg &&
+				(gotFg == wantFg || glyphBlank(cell.Char)) &&
+				(gotCell.Attr&AttrBlink != 0) == blinks
+
+			if !ok {
+				t.Errorf("%s: cell %d,%d is %q %v on %v blinking %v, want %q %v on %v blinking %v", name, x, y,
+					gotCell.Char, gotFg, gotBg, gotCell.Attr&AttrBlink != 0, cell.Char, wantFg, wantBg, blinks)
+				errs++
+			}
+		}
+	}
+
+	// padding is blank
+	for x := c.Width; x < got.Width && errs == 0; x++ {
+		if cell := got.At(x, 0); cell.Char != ' ' {
+			t.Errorf("%s: padding %d,0 is %+v", name, x, cell)
+			errs++
+		}
+	}
+}
@@ -16,9 +16,12 @@
 package goansi
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 // SauceID is the SAUCE record identifier tagged onto the file data
@@ -33,7 +36,7 @@ type SauceInfo struct {
 	Group    [20]byte
 	Date     [8]byte
 	FileSize int32
-	DataType byte
+	DataType DataType
 	FileType byte
 	Tinfo1   uint16
 	Tinfo2   uint16
@@ -41,7 +44,7 @@ type SauceInfo struct {
 	Tinfo4   uint16
 	Comments byte
 	Flags    byte
-	Filler   [22]byte
+	Filler   [22]byte // TInfoS of SAUCE 00.5, the font name of text files, see FontName
 }
 
 // Sauce - container structure for sauceInfo and variable length comments
@@ -56,81 +59,280 @@ const recordSize = 128
 const commentSize = 64
 const commentID = "COMNT"
 
-// TODO: Put this check function into a utils file
-// Simple error checking to reduce duplicated code
-func check(e error) {
-	if e != nil {
-		panic(e)
+// ReadSauce reads the SAUCE record at the end of r along with its comments,
+// or returns ErrNoSauce if r doesn't end with one. The comment lines and the
+// strings returned by Title, Author and Group have their padding trimmed.
+// The position of r afterwards is undefined.
+func ReadSauce(r io.ReadSeeker) (*Sauce, error) {
+	record, err := readRecord(r)
+	if err != nil {
+		return nil, err
 	}
-}
-
-// ReadFileName reads SAUCE via a filename.
-func readFileName(fileName string) *Sauce {
-	file, err := os.Open(fileName)
-	check(err)
 
-	record := readFile(file)
-	file.Close()
+	if string(record.Sauceinf.ID[:]) != SauceID {
+		return nil, ErrNoSauce
+	}
 
-	return record
+	return record, nil
 }
 
-// ReadFile - Read SAUCE via a FILE pointer.
-// TODO: This doesn't trap OOM errors
-// TODO: This seems redundant now, can we eliminate this?
-func readFile(file *os.File) *Sauce {
-	record := readRecord(file)
-	return record
+// ReadSauceBytes reads the SAUCE record at the end of data, like ReadSauce
+func ReadSauceBytes(data []byte) (*Sauce, error) {
+	return ReadSauce(bytes.NewReader(data))
 }
 
 // ReadRecord parses a SAUCE record from a data stream
-func readRecord(stream io.ReadSeeker) *Sauce {
-	_, err := stream.Seek(0-recordSize, 2)
+func readRecord(stream io.ReadSeeker) (*Sauce, error) {
+	offset, err := stream.Seek(0-recordSize, 2)
 
 	if err != nil {
-		return nil
+		// too short to hold a record
+		return &Sauce{}, nil
 	}
 
 	var record Sauce
 	var sinfo SauceInfo
 	err = binary.Read(stream, binary.LittleEndian, &sinfo)
-	check(err)
+	if err != nil {
+		return nil, &FormatError{Format: "SAUCE", Offset: offset, Err: ErrTruncated}
+	}
 
 	if string(sinfo.ID[:]) == SauceID {
 		var comments []string
 		if sinfo.Comments > 0 {
-			comments = readComments(stream, int(sinfo.Comments))
+			comments, err = readComments(stream, int(sinfo.Comments))
+			if err != nil {
+				return nil, err
+			}
 		}
 		record = Sauce{Sauceinf: sinfo, CommentLines: comments}
 	} else {
 		record = Sauce{}
 	}
 
-	return &record
+	return &record, nil
+}
+
+// sauceDataSize returns the size of the data of a file that ends with
+// record, without the record, its comment block and the EOF marker before
+// them. A file without the marker or the comment block keeps those bytes.
+func sauceDataSize(data []byte, record *Sauce) int {
+	if record == nil || string(record.Sauceinf.ID[:]) != SauceID || len(data) < recordSize {
+		return len(data)
+	}
+
+	size := len(data) - recordSize
+	if len(record.CommentLines) > 0 {
+		size = max(size-len(commentID)-commentSize*len(record.CommentLines), 0)
+	}
+	if size > 0 && data[size-1] == 0x1A {
+		size--
+	}
+	return size
 }
 
-func readComments(stream io.ReadSeeker, comments int) []string {
+func readComments(stream io.ReadSeeker, comments int) ([]string, error) {
 	var commentLines []string
 
-	_, err := stream.Seek(0-(recordSize+5+commentSize*int64(comments)), 2)
-	check(err)
+	offset, err := stream.Seek(0-(recordSize+int64(len(commentID))+commentSize*int64(comments)), 2)
+	if err != nil {
+		// the comment block would start before the beginning of the file
+		return nil, &FormatError{Format: "SAUCE", Err: ErrBadHeader}
+	}
 
-	ID := make([]byte, 6)
-	stream.Read(ID)
-	idString := string(ID[:6])
+	ID := make([]byte, len(commentID))
+	if _, err := io.ReadFull(stream, ID); err != nil {
+		return nil, &FormatError{Format: "SAUCE", Offset: offset, Err: ErrTruncated}
+	}
 
-	if idString != commentID {
-		return nil
+	if string(ID) != commentID {
+		return nil, nil
 	}
 
-	// TODO Error checking
 	for i := 0; i < comments; i++ {
-		buf := make([]byte, commentSize+1)
+		buf := make([]byte, commentSize)
+
+		if _, err := io.ReadFull(stream, buf); err != nil {
+			return nil, &FormatError{Format: "SAUCE", Offset: offset, Err: ErrTruncated}
+		}
+
+		commentLines = append(commentLines, sauceText(buf))
+	}
+
+	return commentLines, nil
+}
+
+// Title returns the title of the record without its padding
+func (s *Sauce) Title() string {
+	return sauceText(s.Sauceinf.Title[:])
+}
+
+// Author returns the author of the record without its padding
+func (s *Sauce) Author() string {
+	return sauceText(s.Sauceinf.Author[:])
+}
+
+// Group returns the group of the record without its padding
+func (s *Sauce) Group() string {
+	return sauceText(s.Sauceinf.Group[:])
+}
+
+// SetTitle sets the title of the record, cut to its 35 characters
+func (s *Sauce) SetTitle(title string) {
+	sauceString(s.Sauceinf.Title[:], title)
+}
+
+// SetAuthor sets the author of the record, cut to its 20 characters
+func (s *Sauce) SetAuthor(author string) {
+	sauceString(s.Sauceinf.Author[:], author)
+}
+
+// SetGroup sets the group of the record, cut to its 20 characters
+func (s *Sauce) SetGroup(group string) {
+	sauceString(s.Sauceinf.Group[:], group)
+}
+
+// sauceString fills a SAUCE string field with s in code page 437, padded
+// with spaces
+func sauceString(field []byte, s string) {
+	n := 0
+	for _, r := range s {
+		if n == len(field) {
+			break
+		}
+		field[n] = cp437Byte(r)
+		n++
+	}
+
+	for ; n < len(field); n++ {
+		field[n] = ' '
+	}
+}
+
+// sauceText returns a SAUCE string field in code page 437 as a string,
+// without the spaces and NULs it is padded with
+func sauceText(field []byte) string {
+	field = bytes.TrimRight(field, " \x00")
 
-		stream.Read(buf)
+	text := make([]rune, len(field))
+	for i, b := range field {
+		if b < 0x80 {
+			text[i] = rune(b)
+		} else {
+			text[i] = cp437[b]
+		}
+	}
+
+	return string(text)
+}
+
+// WriteSauce writes what follows the data of a file with a SAUCE record: the
+// EOF marker, the comment block if there are comment lines, and the record.
+// The ID and the number of comments are filled in, a missing version
+// becomes "00". Comment lines are cut to 64 characters, a record
+// holds no more than 255 of them.
+func WriteSauce(w io.Writer, record Sauce) error {
+	if len(record.CommentLines) > 255 {
+		return &OpError{Op: "write SAUCE", Err: ErrBadOption}
+	}
+
+	info := record.Sauceinf
+	copy(info.ID[:], SauceID)
+	if info.Version == [2]byte{} {
+		copy(info.Version[:], "00")
+	}
+	info.Comments = byte(len(record.CommentLines))
+
+	var buf bytes.Buffer
+	buf.WriteByte(0x1A)
+
+	if len(record.CommentLines) > 0 {
+		buf.WriteString(commentID)
+		for _, line := range record.CommentLines {
+			comment := make([]byte, commentSize)
+			sauceString(comment, line)
+			buf.Write(comment)
+		}
+	}
+
+	if err := binary.Write(&buf, binary.LittleEndian, info); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// StripSauce returns the data of a file without record, which is read from
+// its end, along with the comments and the EOF marker before them. Data
+// without a record is returned as it is.
+func StripSauce(data []byte, record *Sauce) []byte {
+	return data[:sauceDataSize(data, record)]
+}
+
+// UpdateSauce changes the SAUCE record of a file with update, which gets the
+// record of the file or a new one dated today, with the type of the data
+// as Detect tells it and the file name suffix hints, and writes the file again
+// with the changed record replacing the old one. FileSize is set to the
+// size of the data. The file is replaced at once, it is never left half
+// written.
+func UpdateSauce(path string, update func(*Sauce)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	record, err := readRecord(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if string(record.Sauceinf.ID[:]) == SauceID {
+		data = StripSauce(data, record)
+	} else {
+		record = &Sauce{}
+		record.Sauceinf.SetTime(time.Now())
+
+		// the type of the data, BIN files as wide as they are read without
+		// a record
+		record.Sauceinf.SetType(formatFileType(detectFormat(data, nil, filepath.Ext(path))))
+		if record.Sauceinf.Type() == FileBinaryText {
+			record.Sauceinf.FileType = 160 / 2
+		}
+	}
+
+	update(record)
+	record.Sauceinf.FileSize = int32(len(data))
 
-		commentLines = append(commentLines, string(buf[:]))
+	var buf bytes.Buffer
+	buf.Write(data)
+	if err := WriteSauce(&buf, *record); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	// write a copy next to the file and move it over the file
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
 
-	return commentLines
+	return os.Rename(tmp.Name(), path)
 }
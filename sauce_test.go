@@ -0,0 +1,293 @@
+//  sauce_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRecord returns a record of a 80x2 ANSi file with comments
+func testRecord(comments ...string) Sauce {
+	var record Sauce
+	record.SetTitle("Title")
+	record.SetAuthor("Author")
+	record.SetGroup("Group")
+	record.Sauceinf.SetType(FileANSi)
+	record.Sauceinf.Tinfo1 = 80
+	record.Sauceinf.Tinfo2 = 2
+	record.CommentLines = comments
+	return record
+}
+
+func TestWriteSauce(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSauce(&buf, testRecord("first", "second")); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// the EOF marker, the comment block and the record
+	if want := 1 + len(commentID) + 2*commentSize + recordSize; len(data) != want {
+		t.Fatalf("got %d bytes, want %d", len(data), want)
+	}
+	if data[0] != 0x1A || string(data[1:6]) != commentID {
+		t.Errorf("got %q before the comments", data[:6])
+	}
+	if comment := string(data[6 : 6+commentSize]); comment != "first"+strings.Repeat(" ", commentSize-5) {
+		t.Errorf("got comment %q", comment)
+	}
+
+	info := data[len(data)-recordSize:]
+	if string(info[:7]) != SauceID+"00" || info[104] != 2 {
+		t.Errorf("got ID and version %q and %d comments", info[:7], info[104])
+	}
+
+	// no comments, no comment block
+	buf.Reset()
+	if err := WriteSauce(&buf, testRecord()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 1+recordSize || buf.Bytes()[0] != 0x1A {
+		t.Errorf("no comments: got %d bytes", buf.Len())
+	}
+
+	if err := WriteSauce(&buf, testRecord(make([]string, 256)...)); !errors.Is(err, ErrBadOption) {
+		t.Errorf("256 comments: got %v, want ErrBadOption", err)
+	}
+}
+
+func TestStripSauce(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("data")
+	if err := WriteSauce(&buf, testRecord("a comment")); err != nil {
+		t.Fatal(err)
+	}
+
+	record := testRecord("a comment")
+	copy(record.Sauceinf.ID[:], SauceID)
+
+	if got := StripSauce(buf.Bytes(), &record); string(got) != "data" {
+		t.Errorf("got %q, want %q", got, "data")
+	}
+	if got := StripSauce([]byte("data"), &Sauce{}); string(got) != "data" {
+		t.Errorf("no record: got %q, want %q", got, "data")
+	}
+
+	// a record right after the data, without the EOF marker or the comment
+	// block it claims
+	buf.Reset()
+	if err := WriteSauce(&buf, testRecord()); err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("x", 75) + "hello"
+	data := append([]byte(text), buf.Bytes()[1:]...)
+	data[len(data)-recordSize+104] = 1
+
+	got, err := ReadSauceBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := StripSauce(data, got); string(data) != text {
+		t.Errorf("no EOF marker: got %q, want %q", data, text)
+	}
+	if c := decodeData(t, data, RenderOptions{Format: FormatANSI}); c.Height != 1 || c.At(79, 0).Char != 'o' {
+		t.Errorf("no EOF marker: decoded %d rows ending in %q", c.Height, c.At(79, 0).Char)
+	}
+	events := lexAll(data)
+	if e := events[len(events)-3]; e.Kind != EventPrint || e.Char != 'o' || events[len(events)-2].Kind != EventSauce {
+		t.Errorf("no EOF marker: got %+v before the record", e)
+	}
+}
+
+func TestUpdateSauce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.ans")
+	if err := os.WriteFile(path, []byte("hello\x1a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() ([]byte, *Sauce) {
+		t.Helper()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record, err := ReadSauceBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data, record
+	}
+
+	// a file without a record gets a new one, dated
+	err := UpdateSauce(path, func(s *Sauce) {
+		s.SetTitle("First")
+		s.CommentLines = []string{"one", "two"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, record := read()
+	if record.Title() != "First" || len(record.CommentLines) != 2 {
+		t.Errorf("got title %q and comments %q", record.Title(), record.CommentLines)
+	}
+	if _, ok := record.Sauceinf.Time(); !ok {
+		t.Errorf("got date %q", record.Sauceinf.Date)
+	}
+	if record.Sauceinf.Type() != FileANSi {
+		t.Errorf("got type %v, want %v", record.Sauceinf.Type(), FileANSi)
+	}
+	if got := StripSauce(data, record); string(got) != "hello\x1a" || record.Sauceinf.FileSize != int32(len(got)) {
+		t.Errorf("got data %q of FileSize %d", got, record.Sauceinf.FileSize)
+	}
+
+	// the record and its comments are replaced, the data is kept
+	err = UpdateSauce(path, func(s *Sauce) {
+		if s.Title() != "First" {
+			t.Errorf("update got title %q", s.Title())
+		}
+		s.SetAuthor("Second")
+		s.CommentLines = nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, record = read()
+	if record.Title() != "First" || record.Author() != "Second" || len(record.CommentLines) != 0 {
+		t.Errorf("got title %q, author %q and comments %q", record.Title(), record.Author(), record.CommentLines)
+	}
+	if want := len("hello\x1a") + 1 + recordSize; len(data) != want {
+		t.Errorf("got %d bytes, want %d", len(data), want)
+	}
+
+	// the types of other formats, BIN files as wide as they are read
+	dir := t.TempDir()
+	for name, want := range map[string]FileType{"test.bin": FileBinaryText, "test.xb": FileXBin, "test.adf": FileNone} {
+		data := []byte("A\x07B\x07")
+		if name == "test.xb" {
+			data = []byte(xbinID + "\x01\x00\x01\x00\x10\x00A\x07")
+		}
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateSauce(path, func(*Sauce) {}); err != nil {
+			t.Fatal(err)
+		}
+
+		record, err := ReadSauceFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if columns, _ := record.Sauceinf.Size(); record.Sauceinf.Type() != want || (want == FileBinaryText && columns != 160) {
+			t.Errorf("%s: got type %v %d columns wide, want %v", name, record.Sauceinf.Type(), columns, want)
+		}
+	}
+
+	if err := UpdateSauce(filepath.Join(t.TempDir(), "missing"), func(*Sauce) {}); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
+
+func TestReadSauce(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("data")
+	record := testRecord("first", strings.Repeat("x", commentSize))
+	record.Sauceinf.Title[0] = 0x82 // é in code page 437
+	record.Sauceinf.Author[10] = 0  // padded with NULs
+	if err := WriteSauce(&buf, record); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	got, err := ReadSauce(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title() != "éitle" || got.Author() != "Author" || got.Group() != "Group" {
+		t.Errorf("got title %q, author %q and group %q", got.Title(), got.Author(), got.Group())
+	}
+	if len(got.CommentLines) != 2 || got.CommentLines[0] != "first" || got.CommentLines[1] != strings.Repeat("x", commentSize) {
+		t.Errorf("got comments %q", got.CommentLines)
+	}
+	if columns, lines := got.Sauceinf.Size(); got.Sauceinf.Type() != FileANSi || columns != 80 || lines != 2 {
+		t.Errorf("got %v %dx%d", got.Sauceinf.Type(), columns, lines)
+	}
+
+	// a record whose comment block isn't there is read without comments
+	noComments := append(bytes.Repeat([]byte{' '}, 200), data[len(data)-recordSize:]...)
+	if got, err := ReadSauceBytes(noComments); err != nil || len(got.CommentLines) != 0 {
+		t.Errorf("missing comment block: got %v, %v", got, err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrNoSauce},
+		{"short", []byte("SAUCE00"), ErrNoSauce},
+		{"no record", bytes.Repeat([]byte{' '}, 2*recordSize), ErrNoSauce},
+		{"comments before the start", data[len(data)-recordSize:], ErrBadHeader},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadSauceBytes(tt.data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSkipHints(t *testing.T) {
+	record := testRecord()
+	record.Sauceinf.Tinfo1 = 40
+	record.Sauceinf.Flags = byte(FlagNonBlink | FlagLetter9)
+
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[5;44mX")
+	if err := WriteSauce(&buf, record); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		skip  SauceHint
+		hints SauceHint
+		width int
+		bits  int
+		blink bool
+	}{
+		{0, HintColumns | HintIceColors | HintBits, 40, 9, false},
+		{HintIceColors, HintColumns | HintBits, 40, 9, true},
+		{HintColumns | HintBits, HintIceColors, 80, 8, false},
+	}
+
+	for _, tt := range tests {
+		opts := RenderOptions{Format: FormatANSI, UseSauceHints: true, SkipHints: tt.skip}
+		result, err := Decode(context.Background(), bytes.NewReader(buf.Bytes()), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := result.Canvas
+		blink := c.At(0, 0).Attr&AttrBlink != 0
+		if result.Hints != tt.hints || c.Width != tt.width || c.Bits != tt.bits || blink != tt.blink {
+			t.Errorf("skip %v: got hints %v, %d columns of %d bits blinking %v", tt.skip, result.Hints, c.Width, c.Bits, blink)
+		}
+	}
+}
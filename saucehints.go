@@ -0,0 +1,160 @@
+//  saucehints.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SauceHint is a set of render options taken from a SAUCE record
+type SauceHint int
+
+// SAUCE hints
+const (
+	HintColumns   SauceHint = 1 << iota // the width of ANSi, BIN and Tundra files
+	HintIceColors                       // iCE colors of the ANSiFlags
+	HintBits                            // the letter spacing of the ANSiFlags
+	HintAspect                          // the legacy aspect ratio of the ANSiFlags
+	HintFont                            // the font named by the record, see FontName
+)
+
+var sauceHintNames = []string{"columns", "iCE colors", "bits", "aspect ratio", "font"}
+
+func (h SauceHint) String() string {
+	var names []string
+	for i, name := range sauceHintNames {
+		if h&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// sauceMaxColumns is the widest file a hint may ask for, the most the
+// go-ansi command allows
+const sauceMaxColumns = 8192
+
+// sauceFonts maps the SAUCE names of Amiga fonts to embedded fonts
+var sauceFonts = map[string]string{
+	"Amiga Topaz 1":      "topaz500",
+	"Amiga Topaz 1+":     "topaz500+",
+	"Amiga Topaz 2":      "topaz",
+	"Amiga Topaz 2+":     "topaz+",
+	"Amiga P0T-NOoDLE":   "pot-noodle",
+	"Amiga MicroKnight":  "microknight",
+	"Amiga MicroKnight+": "microknight+",
+	"Amiga mOsOul":       "mosoul",
+}
+
+// sauceFont returns the embedded font closest to a SAUCE font name such as
+// "IBM VGA 437" or "Amiga Topaz 2+", or nothing for fonts there is no match
+// for. The code page of IBM fonts matters more than their height, only code
+// page 437 comes in more than one height.
+func sauceFont(name string) string {
+	if font, ok := sauceFonts[name]; ok {
+		return font
+	}
+
+	fields := strings.Fields(name)
+	if len(fields) < 2 || len(fields) > 3 || fields[0] != "IBM" {
+		return ""
+	}
+
+	// fonts of 8 pixel high characters
+	small := false
+	switch fields[1] {
+	case "VGA", "VGA25G", "EGA":
+	case "VGA50", "EGA43":
+		small = true
+	default:
+		return ""
+	}
+
+	codePage := 437
+	if len(fields) == 3 {
+		n, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return ""
+		}
+		codePage = n
+	}
+
+	if codePage == 437 {
+		if small {
+			return "80x50"
+		}
+		return "80x25"
+	}
+
+	for font, cp := range fontCodePages {
+		if cp == codePage {
+			return font
+		}
+	}
+	return ""
+}
+
+// sauceHints fills the options left unset with the hints of record for a
+// file decoded as format, columns is set to the width of ANSi files. The
+// hints in opts.SkipHints aren't taken. It returns the hints that were.
+func sauceHints(opts *RenderOptions, columns *int, record *SauceInfo, format Format) SauceHint {
+	var hints SauceHint
+
+	take := func(hint SauceHint) bool {
+		return opts.SkipHints&hint == 0
+	}
+
+	if opts.Columns == 0 && take(HintColumns) {
+		width, _ := record.Size()
+		if width > 0 && width <= sauceMaxColumns {
+			switch format {
+			case FormatBinary, FormatTundra:
+				opts.Columns = width
+				hints |= HintColumns
+			case FormatANSI, FormatDIZ:
+				*columns = width
+				hints |= HintColumns
+			}
+		}
+	}
+
+	if flags, ok := record.ANSiFlags(); ok {
+		if !opts.IceColors && flags.IceColors() && take(HintIceColors) {
+			opts.IceColors = true
+			hints |= HintIceColors
+		}
+		if bits := flags.LetterSpacing(); opts.Bits == 0 && bits != 0 && take(HintBits) {
+			opts.Bits = bits
+			hints |= HintBits
+		}
+		if !opts.Stretch && flags.AspectRatio() == AspectLegacy && take(HintAspect) {
+			opts.Stretch = true
+			hints |= HintAspect
+		}
+
+		if font := sauceFont(record.FontName()); opts.Font == "" && font != "" && take(HintFont) {
+			opts.Font = font
+			hints |= HintFont
+		}
+	}
+
+	return hints
+}
+
+// stretchFactor returns how much taller the image of a canvas is shown with
+// the legacy aspect ratio: 80 columns of 8 or 9 pixels by 400 lines filling
+// a 4:3 screen
+func stretchFactor(bits int) float32 {
+	return float32(80*bits*3) / float32(400*4)
+}
@@ -0,0 +1,382 @@
+//  saucetype.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DataType is the kind of data a SAUCE record describes
+type DataType byte
+
+// SAUCE 00.5 data types
+const (
+	DataTypeNone DataType = iota
+	DataTypeCharacter
+	DataTypeBitmap
+	DataTypeVector
+	DataTypeAudio
+	DataTypeBinaryText
+	DataTypeXBin
+	DataTypeArchive
+	DataTypeExecutable
+)
+
+var dataTypeNames = []string{
+	"None", "Character", "Bitmap", "Vector", "Audio", "BinaryText", "XBin",
+	"Archive", "Executable",
+}
+
+func (t DataType) String() string {
+	if int(t) >= len(dataTypeNames) {
+		return "DataType " + strconv.Itoa(int(t))
+	}
+	return dataTypeNames[t]
+}
+
+// FileType is a SAUCE file type together with its data type, the same file
+// type number means something else for each data type
+type FileType uint16
+
+// fileType returns the FileType of a SAUCE data type and file type number
+func fileType(d DataType, n byte) FileType {
+	return FileType(d)<<8 | FileType(n)
+}
+
+// DataType returns the data type the file type belongs to
+func (f FileType) DataType() DataType {
+	return DataType(f >> 8)
+}
+
+// SAUCE 00.5 file types without data
+const (
+	FileNone FileType = FileType(DataTypeNone) << 8
+)
+
+// SAUCE 00.5 character file types
+const (
+	FileASCII FileType = FileType(DataTypeCharacter)<<8 + iota
+	FileANSi
+	FileANSiMation
+	FileRIPScript
+	FilePCBoard
+	FileAvatar
+	FileHTML
+	FileSource
+	FileTundraDraw
+)
+
+// SAUCE 00.5 bitmap file types
+const (
+	FileGIF FileType = FileType(DataTypeBitmap)<<8 + iota
+	FilePCX
+	FileLBM
+	FileTGA
+	FileFLI
+	FileFLC
+	FileBMP
+	FileGL
+	FileDL
+	FileWPGBitmap
+	FilePNG
+	FileJPG
+	FileMPG
+	FileAVI
+)
+
+// SAUCE 00.5 vector file types
+const (
+	FileDXF FileType = FileType(DataTypeVector)<<8 + iota
+	FileDWG
+	FileWPGVector
+	File3DS
+)
+
+// SAUCE 00.5 audio file types
+const (
+	FileMOD FileType = FileType(DataTypeAudio)<<8 + iota
+	File669
+	FileSTM
+	FileS3M
+	FileMTM
+	FileFAR
+	FileULT
+	FileAMF
+	FileDMF
+	FileOKT
+	FileROL
+	FileCMF
+	FileMID
+	FileSADT
+	FileVOC
+	FileWAV
+	FileSMP8
+	FileSMP8S
+	FileSMP16
+	FileSMP16S
+	FilePATCH8
+	FilePATCH16
+	FileXM
+	FileHSC
+	FileIT
+)
+
+// SAUCE 00.5 file types of the data types that have a single one. The file
+// type number of BinaryText is half its width instead.
+const (
+	FileBinaryText FileType = FileType(DataTypeBinaryText) << 8
+	FileXBin       FileType = FileType(DataTypeXBin) << 8
+	FileExecutable FileType = FileType(DataTypeExecutable) << 8
+)
+
+// SAUCE 00.5 archive file types
+const (
+	FileZIP FileType = FileType(DataTypeArchive)<<8 + iota
+	FileARJ
+	FileLZH
+	FileARC
+	FileTAR
+	FileZOO
+	FileRAR
+	FileUC2
+	FilePAK
+	FileSQZ
+)
+
+var fileTypeNames = map[FileType]string{
+	FileNone: "None",
+
+	FileASCII: "ASCII", FileANSi: "ANSi", FileANSiMation: "ANSiMation",
+	FileRIPScript: "RIP script", FilePCBoard: "PCBoard", FileAvatar: "Avatar",
+	FileHTML: "HTML", FileSource: "Source", FileTundraDraw: "TundraDraw",
+
+	FileGIF: "GIF", FilePCX: "PCX", FileLBM: "LBM/IFF", FileTGA: "TGA",
+	FileFLI: "FLI", FileFLC: "FLC", FileBMP: "BMP", FileGL: "GL", FileDL: "DL",
+	FileWPGBitmap: "WPG", FilePNG: "PNG", FileJPG: "JPG", FileMPG: "MPG",
+	FileAVI: "AVI",
+
+	FileDXF: "DXF", FileDWG: "DWG", FileWPGVector: "WPG", File3DS: "3DS",
+
+	FileMOD: "MOD", File669: "669", FileSTM: "STM", FileS3M: "S3M",
+	FileMTM: "MTM", FileFAR: "FAR", FileULT: "ULT", FileAMF: "AMF",
+	FileDMF: "DMF", FileOKT: "OKT", FileROL: "ROL", FileCMF: "CMF",
+	FileMID: "MID", FileSADT: "SADT", FileVOC: "VOC", FileWAV: "WAV",
+	FileSMP8: "SMP8", FileSMP8S: "SMP8S", FileSMP16: "SMP16",
+	FileSMP16S: "SMP16S", FilePATCH8: "PATCH8", FilePATCH16: "PATCH16",
+	FileXM: "XM", FileHSC: "HSC", FileIT: "IT",
+
+	FileBinaryText: "BinaryText", FileXBin: "XBin", FileExecutable: "Executable",
+
+	FileZIP: "ZIP", FileARJ: "ARJ", FileLZH: "LZH", FileARC: "ARC",
+	FileTAR: "TAR", FileZOO: "ZOO", FileRAR: "RAR", FileUC2: "UC2",
+	FilePAK: "PAK", FileSQZ: "SQZ",
+}
+
+func (f FileType) String() string {
+	if name, ok := fileTypeNames[f]; ok {
+		return name
+	}
+	return f.DataType().String() + " " + strconv.Itoa(int(f&0xFF))
+}
+
+// Type returns the file type of the record
+func (s *SauceInfo) Type() FileType {
+	if s.DataType == DataTypeBinaryText {
+		return FileBinaryText
+	}
+	return fileType(s.DataType, s.FileType)
+}
+
+// formatFileType returns the SAUCE file type of files of format f, or
+// FileNone for the formats SAUCE has no type for
+func formatFileType(f Format) FileType {
+	switch f {
+	case FormatANSI, FormatDIZ:
+		return FileANSi
+	case FormatPCBoard:
+		return FilePCBoard
+	case FormatTundra:
+		return FileTundraDraw
+	case FormatBinary:
+		return FileBinaryText
+	case FormatXBin:
+		return FileXBin
+	}
+	return FileNone
+}
+
+// SetType sets the data type and file type of the record
+func (s *SauceInfo) SetType(f FileType) {
+	s.DataType = f.DataType()
+	s.FileType = byte(f)
+}
+
+// ANSiFlags are the flags of SAUCE records for text shown with a font
+type ANSiFlags byte
+
+// ANSiFlags bits
+const (
+	FlagNonBlink     ANSiFlags = 1      // iCE colors, bright backgrounds instead of blinking
+	FlagLetter8      ANSiFlags = 1 << 1 // 8 pixel wide characters
+	FlagLetter9      ANSiFlags = 2 << 1 // 9 pixel wide characters
+	FlagAspectLegacy ANSiFlags = 1 << 3 // stretched as on the legacy device
+	FlagAspectSquare ANSiFlags = 2 << 3 // square pixels
+
+	flagLetterMask = 3 << 1
+	flagAspectMask = 3 << 3
+)
+
+// IceColors reports whether the background colors are bright instead of
+// blinking
+func (f ANSiFlags) IceColors() bool {
+	return f&FlagNonBlink != 0
+}
+
+// LetterSpacing returns the width of the characters in pixels, 8 or 9, or 0
+// if the record doesn't say
+func (f ANSiFlags) LetterSpacing() int {
+	switch f & flagLetterMask {
+	case FlagLetter8:
+		return 8
+	case FlagLetter9:
+		return 9
+	}
+	return 0
+}
+
+// AspectRatio is the aspect ratio ANSiFlags ask the pixels to be shown with
+type AspectRatio int
+
+// Aspect ratios of ANSiFlags
+const (
+	AspectNone   AspectRatio = iota // no preference
+	AspectLegacy                    // stretched as on the legacy device
+	AspectSquare                    // square pixels
+)
+
+var aspectRatioNames = []string{"none", "legacy", "square"}
+
+func (a AspectRatio) String() string {
+	if a < 0 || int(a) >= len(aspectRatioNames) {
+		return "unknown"
+	}
+	return aspectRatioNames[a]
+}
+
+// AspectRatio returns the aspect ratio the pixels are shown with
+func (f ANSiFlags) AspectRatio() AspectRatio {
+	switch f & flagAspectMask {
+	case FlagAspectLegacy:
+		return AspectLegacy
+	case FlagAspectSquare:
+		return AspectSquare
+	}
+	return AspectNone
+}
+
+func (f ANSiFlags) String() string {
+	var names []string
+	if f.IceColors() {
+		names = append(names, "iCE colors")
+	}
+	if n := f.LetterSpacing(); n != 0 {
+		names = append(names, strconv.Itoa(n)+" pixel font")
+	}
+	if a := f.AspectRatio(); a != AspectNone {
+		names = append(names, a.String()+" aspect ratio")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// ANSiFlags returns the flags of the record, ok is false for the file types
+// whose flags mean nothing
+func (s *SauceInfo) ANSiFlags() (flags ANSiFlags, ok bool) {
+	switch s.Type() {
+	case FileASCII, FileANSi, FileANSiMation, FileBinaryText:
+		return ANSiFlags(s.Flags), true
+	}
+	return 0, false
+}
+
+// FontName returns the name of the font the record asks for, such as
+// "IBM VGA", or nothing
+func (s *SauceInfo) FontName() string {
+	name := s.Filler[:]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(string(name))
+}
+
+// SetFontName sets the name of the font, cut to its 22 characters
+func (s *SauceInfo) SetFontName(name string) {
+	s.Filler = [22]byte{}
+	if len(name) > len(s.Filler) {
+		name = name[:len(s.Filler)]
+	}
+	copy(s.Filler[:], name)
+}
+
+// sauceDateLayout is the CCYYMMDD layout of SAUCE dates
+const sauceDateLayout = "20060102"
+
+// Time returns the date of the record, ok is false when it isn't a date
+func (s *SauceInfo) Time() (t time.Time, ok bool) {
+	t, err := time.Parse(sauceDateLayout, strings.TrimSpace(string(s.Date[:])))
+	return t, err == nil
+}
+
+// SetTime sets the date of the record to the day of t
+func (s *SauceInfo) SetTime(t time.Time) {
+	copy(s.Date[:], t.Format(sauceDateLayout))
+}
+
+// TinfoNames returns what Tinfo1 to Tinfo4 hold for the file type of the
+// record, the ones that hold nothing are empty
+func (s *SauceInfo) TinfoNames() [4]string {
+	switch s.Type() {
+	case FileASCII, FileANSi, FileANSiMation, FilePCBoard, FileAvatar,
+		FileTundraDraw, FileXBin:
+		return [4]string{"Character width", "Number of lines"}
+	case FileRIPScript:
+		return [4]string{"Pixel width", "Pixel height", "Number of colors"}
+	case FileSMP8, FileSMP8S, FileSMP16, FileSMP16S:
+		return [4]string{"Sample rate"}
+	}
+
+	if s.DataType == DataTypeBitmap {
+		return [4]string{"Pixel width", "Pixel height", "Pixel depth"}
+	}
+	return [4]string{}
+}
+
+// Size returns the size in characters of text files, either of them is 0
+// when the record doesn't say. The width of BinaryText is twice its file
+// type number, the number of lines follows from the file size.
+func (s *SauceInfo) Size() (columns, lines int) {
+	switch s.Type() {
+	case FileASCII, FileANSi, FileANSiMation, FilePCBoard, FileAvatar,
+		FileTundraDraw, FileXBin:
+		return int(s.Tinfo1), int(s.Tinfo2)
+	case FileBinaryText:
+		columns = 2 * int(s.FileType)
+		if columns > 0 && s.FileSize > 0 {
+			lines = int(s.FileSize) / (2 * columns)
+		}
+		return columns, lines
+	}
+	return 0, 0
+}
@@ -0,0 +1,212 @@
+//  svg.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// EncodeSVG writes the canvas as an SVG image of the size Draw renders.
+// Backgrounds are rectangles, one for every run of cells of a color, drawn
+// over a single rectangle of the most common one. Every glyph is defined once
+// as a path traced from the font bitmap and placed with <use>. Elements are
+// grouped by color. Blinking text is drawn visible.
+func (c *Canvas) EncodeSVG(w io.Writer) error {
+	bits := c.Bits
+	if bits == 0 {
+		bits = 8
+	}
+	height := c.Font.Height
+
+	// transparent cells leave no room for a rectangle under everything
+	counts := make(map[color.RGBA]int)
+	for _, cell := range c.Cells {
+		_, bg := c.CellColors(cell)
+		counts[bg]++
+	}
+
+	var base color.RGBA
+	if counts[color.RGBA{}] == 0 {
+		for bg, n := range counts {
+			if n > counts[base] || (n == counts[base] && colorLess(bg, base)) {
+				base = bg
+			}
+		}
+	}
+
+	var defs bytes.Buffer
+	glyphs := make(map[glyphKey]int)
+	backgrounds := newSVGGroups()
+	foregrounds := newSVGGroups()
+
+	for y := 0; y < c.Height; y++ {
+		runStart := 0
+
+		for x := 0; x < c.Width; x++ {
+			cell := c.Cells[y*c.Width+x]
+			fg, bg := c.CellColors(cell)
+
+			// the run ends at the last column or before a cell of another background
+			if x == c.Width-1 || c.cellBackground(x+1, y) != bg {
+				if bg.A > 0 && bg != base {
+					fmt.Fprintf(backgrounds.add(bg), "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\"/>\n",
+						runStart*bits, y*height, (x+1-runStart)*bits, height)
+				}
+				runStart = x + 1
+			}
+
+			key := glyphKey{cell.Char, cell.Attr & glyphAttrs}
+			id, ok := glyphs[key]
+			if !ok {
+				id = -1
+				if path := c.glyphPath(key, bits); path != "" {
+					id = len(glyphs)
+					fmt.Fprintf(&defs, "<path id=\"g%d\" d=\"%s\"/>\n", id, path)
+				}
+				glyphs[key] = id
+			}
+
+			// blank glyphs are left out
+			if id >= 0 && fg != bg {
+				fmt.Fprintf(foregrounds.add(fg), "<use xlink:href=\"#g%d\" x=\"%d\" y=\"%d\"/>\n", id, x*bits, y*height)
+			}
+		}
+	}
+
+	width := c.Width * bits
+	imageHeight := c.Height * height
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" "+
+		"width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" shape-rendering=\"crispEdges\">\n",
+		width, imageHeight, width, imageHeight)
+
+	fmt.Fprintf(bw, "<defs>\n%s</defs>\n", defs.Bytes())
+
+	if base.A > 0 {
+		fmt.Fprintf(bw, "<rect width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", width, imageHeight, cssColor(base))
+	}
+
+	backgrounds.write(bw)
+	foregrounds.write(bw)
+
+	bw.WriteString("</svg>\n")
+
+	return bw.Flush()
+}
+
+// cellBackground returns the background color cell x, y is drawn with
+func (c *Canvas) cellBackground(x, y int) color.RGBA {
+	_, bg := c.CellColors(c.At(x, y))
+	return bg
+}
+
+// colorLess orders colors, to pick between equally common ones the same way
+// every time
+func colorLess(a, b color.RGBA) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	return a.B < b.B
+}
+
+// glyphPath traces the pixels of a glyph as rectangles, one for each run of
+// pixels on a line, extended down over the lines that repeat it. Blank
+// glyphs have an empty path.
+func (c *Canvas) glyphPath(key glyphKey, bits int) string {
+	height := c.Font.Height
+
+	im := image.NewRGBA(image.Rect(0, 0, bits, height))
+	alDrawChar(im, c.Font.Data, bits, height, 0, 0, color.RGBA{}, color.RGBA{255, 255, 255, 255}, key.char, key.attr)
+
+	used := make([]bool, bits*height)
+	free := func(x, y int) bool {
+		return !used[y*bits+x] && im.Pix[im.PixOffset(x, y)+3] > 0
+	}
+
+	var path bytes.Buffer
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < bits; x++ {
+			if !free(x, y) {
+				continue
+			}
+
+			x1 := x + 1
+			for x1 < bits && free(x1, y) {
+				x1++
+			}
+
+			y1 := y + 1
+			for ; y1 < height; y1++ {
+				run := true
+				for i := x; i < x1 && run; i++ {
+					run = free(i, y1)
+				}
+				if !run {
+					break
+				}
+			}
+
+			for j := y; j < y1; j++ {
+				for i := x; i < x1; i++ {
+					used[j*bits+i] = true
+				}
+			}
+
+			fmt.Fprintf(&path, "M%d %dh%dv%dh-%dz", x, y, x1-x, y1-y, x1-x)
+			x = x1 - 1
+		}
+	}
+
+	return path.String()
+}
+
+// svgGroups collects elements by their fill color, in the order the colors
+// first appear
+type svgGroups struct {
+	index map[color.RGBA]int
+	fills []color.RGBA
+	elems []*bytes.Buffer
+}
+
+func newSVGGroups() *svgGroups {
+	return &svgGroups{index: make(map[color.RGBA]int)}
+}
+
+// add returns the buffer for elements filled with fill
+func (g *svgGroups) add(fill color.RGBA) *bytes.Buffer {
+	i, ok := g.index[fill]
+	if !ok {
+		i = len(g.fills)
+		g.index[fill] = i
+		g.fills = append(g.fills, fill)
+		g.elems = append(g.elems, &bytes.Buffer{})
+	}
+	return g.elems[i]
+}
+
+func (g *svgGroups) write(w *bufio.Writer) {
+	for i, fill := range g.fills {
+		fmt.Fprintf(w, "<g fill=\"%s\">\n", cssColor(fill))
+		w.Write(g.elems[i].Bytes())
+		w.WriteString("</g>\n")
+	}
+}
@@ -0,0 +1,117 @@
+//  terminal.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"context"
+	"image"
+	"sync"
+)
+
+// TerminalOptions controls the screen of a Terminal. The zero value is an
+// 80x25 screen with the default font.
+type TerminalOptions struct {
+	Width     int     // number of columns (default: 80)
+	Height    int     // number of rows (default: 25)
+	Font      string  // font name, e.g. "80x25" or "topaz+" (default: 80x25)
+	Bits      int     // character cell width, 8 or 9 (default: 8)
+	Mode      string  // rendering mode: "ced", "transparent" or "workbench"
+	IceColors bool    // use iCE colors instead of blinking
+	Palette   Palette // 256 color palette in xterm order (default: XtermPalette())
+}
+
+// Terminal is a text mode screen that ANSi data is written to as it arrives,
+// e.g. from a live BBS session. Escape sequences may be split across writes.
+// Unlike rendering a file, the screen keeps its size and scrolls when text
+// runs off the bottom. A Terminal is safe for concurrent use.
+type Terminal struct {
+	mu          sync.Mutex
+	lex         lexer
+	in          *interpreter
+	font        *Font
+	bits        int
+	palette     Palette
+	transparent bool
+}
+
+// NewTerminal returns a Terminal with a blank screen and the cursor at the
+// top left
+func NewTerminal(opts TerminalOptions) *Terminal {
+	if opts.Width < 1 {
+		opts.Width = 80
+	}
+	if opts.Height < 1 {
+		opts.Height = 25
+	}
+	if opts.Bits == 0 {
+		opts.Bits = 8
+	}
+
+	font := SelectFont(opts.Font)
+
+	var base Palette
+	if opts.Mode == "workbench" {
+		base = workbenchPalette
+	}
+
+	return &Terminal{
+		in:          newTerminalInterpreter(opts.Width, opts.Height, opts.Mode, opts.IceColors, font.Amiga),
+		font:        font,
+		bits:        opts.Bits,
+		palette:     ansiPalette(opts.Palette, base),
+		transparent: opts.Mode == "transparent",
+	}
+}
+
+// Write updates the screen with p. It never fails.
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range p {
+		t.lex.feed(c, t.in.handle)
+	}
+
+	return len(p), nil
+}
+
+// Cursor returns the column and row of the cursor
+func (t *Terminal) Cursor() (x, y int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// a full line leaves the cursor past the last column until the next character
+	return min(t.in.x, t.in.page.width-1), t.in.y
+}
+
+// Canvas returns a copy of the current screen
+func (t *Terminal) Canvas() *Canvas {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	screen := t.in.page
+
+	canvas := NewCanvas(screen.width, screen.height, t.font, t.palette.clone())
+	canvas.Bits = t.bits
+	canvas.Transparent = t.transparent
+
+	for y, row := range screen.rows {
+		copy(canvas.Cells[y*canvas.Width:], row)
+	}
+
+	return canvas
+}
+
+// Image draws the current screen
+func (t *Terminal) Image() *image.RGBA {
+	// a background context can't be canceled, Draw doesn't fail
+	im, _ := t.Canvas().Draw(context.Background())
+	return im
+}
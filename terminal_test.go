@@ -0,0 +1,96 @@
+//  terminal_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"reflect"
+	"testing"
+)
+
+// screenText returns the characters of a canvas row by row
+func screenText(c *Canvas) []string {
+	lines := make([]string, c.Height)
+	for y := range lines {
+		line := make([]byte, c.Width)
+		for x := range line {
+			line[x] = c.At(x, y).Char
+		}
+		lines[y] = string(line)
+	}
+	return lines
+}
+
+func TestTerminal(t *testing.T) {
+	tests := []struct {
+		writes []string
+		lines  []string
+		x, y   int
+	}{
+		{[]string{"ab"}, []string{"ab  ", "    ", "    "}, 2, 0},
+		// text running off the bottom scrolls the screen
+		{[]string{"1\r\n2\r\n3\r\n4"}, []string{"2   ", "3   ", "4   "}, 1, 2},
+		{[]string{"abcdefghijklm"}, []string{"efgh", "ijkl", "m   "}, 1, 2},
+		// a full line leaves the cursor on its last column
+		{[]string{"abcd"}, []string{"abcd", "    ", "    "}, 3, 0},
+		// sequences split across writes
+		{[]string{"a\x1b", "[3;", "2Hb"}, []string{"a   ", "    ", " b  "}, 2, 2},
+		// ED 2 homes the cursor as ANSI.SYS does
+		{[]string{"ab\x1b[2", "J"}, []string{"    ", "    ", "    "}, 0, 0},
+		// positions are kept on the screen
+		{[]string{"\x1b[99;99Hx"}, []string{"    ", "    ", "   x"}, 3, 2},
+		{[]string{"a\r\nb\x1b[H\x1b[L"}, []string{"    ", "a   ", "b   "}, 0, 0},
+		{[]string{"a\r\nb\r\nc\x1b[H\x1b[M"}, []string{"b   ", "c   ", "    "}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		term := NewTerminal(TerminalOptions{Width: 4, Height: 3})
+		for _, w := range tt.writes {
+			if n, err := term.Write([]byte(w)); n != len(w) || err != nil {
+				t.Errorf("%q: Write(%q) = %d, %v", tt.writes, w, n, err)
+			}
+		}
+
+		canvas := term.Canvas()
+		if canvas.Width != 4 || canvas.Height != 3 {
+			t.Errorf("%q: got a %dx%d screen, want 4x3", tt.writes, canvas.Width, canvas.Height)
+			continue
+		}
+		if lines := screenText(canvas); !reflect.DeepEqual(lines, tt.lines) {
+			t.Errorf("%q: got %q, want %q", tt.writes, lines, tt.lines)
+		}
+		if x, y := term.Cursor(); x != tt.x || y != tt.y {
+			t.Errorf("%q: cursor at %d,%d, want %d,%d", tt.writes, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestTerminalDefaults(t *testing.T) {
+	term := NewTerminal(TerminalOptions{})
+	term.Write([]byte("\x1b[1;31mx"))
+
+	canvas := term.Canvas()
+	if canvas.Width != 80 || canvas.Height != 25 || canvas.Bits != 8 {
+		t.Errorf("got a %dx%d screen of %d bit characters, want 80x25 of 8", canvas.Width, canvas.Height, canvas.Bits)
+	}
+	if cell := canvas.At(0, 0); cell.Char != 'x' || cell.Fg != 12 {
+		t.Errorf("got %+v, want a bright red x", cell)
+	}
+
+	// the copy doesn't change with the screen
+	term.Write([]byte("\x1b[Hy"))
+	if canvas.At(0, 0).Char != 'x' {
+		t.Error("Canvas returned the screen instead of a copy")
+	}
+
+	im := term.Image()
+	if b := im.Bounds(); b.Dx() != 80*8 || b.Dy() != 25*canvas.Font.Height {
+		t.Errorf("got a %v image", b)
+	}
+}
@@ -16,87 +16,95 @@
 package goansi
 
 import (
-	"fmt"
-	"image"
+	"context"
 	"image/color"
-	"image/draw"
-	"os"
 )
 
-func tundra(inputFileBuffer []byte, inputFileSize int64, columns int, fontName string, bits int) image.Image {
+// tundraHeader is the header signature following the version byte
+const tundraHeader = "TUNDRA24"
+
+// tundraMaxPosition bounds the cursor positions accepted from a Tundra file
+const tundraMaxPosition = 0xFFFF
+
+// tundraOperandSize returns the number of operand bytes following a Tundra opcode
+func tundraOperandSize(opcode int) int {
+	switch opcode {
+	case 1:
+		return 8
+	case 2, 4:
+		return 5
+	case 6:
+		return 9
+	}
+	return 0
+}
+
+func tundra(ctx context.Context, inputFileBuffer []byte, inputFileSize int64, columns int, fontName string, bits int) (*Canvas, error) {
 	// some type declarations
-	var f font
+	var f Font
 
 	// font selection
 	alSelectFont(&f, fontName)
 
-	// libgd image pointers
-	var imTundra draw.Image
-
 	// extract tundra header
+	if inputFileSize < 1+int64(len(tundraHeader)) {
+		return nil, formatError(FormatTundra, int(inputFileSize), ErrTruncated)
+	}
+
 	tundraVersion := inputFileBuffer[0]
 
-	// need to add check for "TUNDRA24" string in the header
-	if tundraVersion != 24 {
-		fmt.Printf("\nInput file is not a TUNDRA file.\n\n")
-		os.Exit(4)
+	if tundraVersion != 24 || string(inputFileBuffer[1:1+len(tundraHeader)]) != tundraHeader {
+		return nil, formatError(FormatTundra, 0, ErrNotTundra)
 	}
 
 	// read tundra file a first time to find the image size
 	var character, loop, positionX, positionY int
 
+	// the last position opcode, the image ends on its row
+	var lastRowOffset int
+
 	var colorBackground, colorForeground color.RGBA
 
 	loop = 9
 
 	for loop < int(inputFileSize) {
-		if positionX == 80 {
+		if positionX == columns {
 			positionX = 0
 			positionY++
 		}
 
-		character = int(inputFileBuffer[loop])
+		opcode := int(inputFileBuffer[loop])
 
-		if character == 1 {
-			positionY = (int(inputFileBuffer[loop+1]) << 24) + (int(inputFileBuffer[loop+2]) << 16) + (int(inputFileBuffer[loop+3]) << 8) + int(inputFileBuffer[loop+4])
-
-			positionX = (int(inputFileBuffer[loop+5]) << 24) + (int(inputFileBuffer[loop+6]) << 16) + (int(inputFileBuffer[loop+7]) << 8) + int(inputFileBuffer[loop+8])
-
-			loop += 8
-		}
-
-		if character == 2 {
-			character = int(inputFileBuffer[loop+1])
-			loop += 5
+		if loop+tundraOperandSize(opcode) >= int(inputFileSize) {
+			return nil, formatError(FormatTundra, loop, ErrTruncated)
 		}
 
-		if character == 4 {
-			character = int(inputFileBuffer[loop+1])
-			loop += 5
-		}
+		if opcode == 1 {
+			positionY = (int(inputFileBuffer[loop+1]) << 24) + (int(inputFileBuffer[loop+2]) << 16) + (int(inputFileBuffer[loop+3]) << 8) + int(inputFileBuffer[loop+4])
 
-		if character == 6 {
-			character = int(inputFileBuffer[loop+1])
-			loop += 9
-		}
+			positionX = (int(inputFileBuffer[loop+5]) << 24) + (int(inputFileBuffer[loop+6]) << 16) + (int(inputFileBuffer[loop+7]) << 8) + int(inputFileBuffer[loop+8])
 
-		if character != 1 && character != 2 && character != 4 && character != 6 {
+			if positionY > tundraMaxPosition || positionX > tundraMaxPosition {
+				return nil, formatError(FormatTundra, loop, ErrBadHeader)
+			}
+			lastRowOffset = loop
+		} else {
+			// every other opcode draws the character it carries
 			positionX++
 		}
 
-		loop++
+		loop += 1 + tundraOperandSize(opcode)
 	}
 	positionY++
 
-	imTundra = image.NewRGBA(image.Rect(0, 0, columns*bits, positionY*f.sizeY))
-
-	if imTundra == nil {
-		fmt.Printf("\nError, can't allocate buffer image memory.\n\n")
-		os.Exit(6)
+	if !canvasFits(columns, positionY) {
+		return nil, formatError(FormatTundra, lastRowOffset, ErrBadHeader)
 	}
 
-	black := color.RGBA{0, 0, 0, 255}
-	draw.Draw(imTundra, imTundra.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
+	// every cell carries 24-bit colors, the palette only covers cells
+	// written before the first color change
+	canvas := NewCanvas(columns, positionY, &f, vgaPalette.clone())
+	canvas.Bits = bits
 
 	// process tundra
 	positionX = 0
@@ -110,49 +118,45 @@ func tundra(inputFileBuffer []byte, inputFileSize int64, columns int, fontName s
 			positionY++
 		}
 
-		character = int(inputFileBuffer[loop])
+		opcode := int(inputFileBuffer[loop])
+		character = opcode
 
-		if character == 1 {
+		switch opcode {
+		case 1:
 			positionY = (int(inputFileBuffer[loop+1]) << 24) + (int(inputFileBuffer[loop+2]) << 16) + (int(inputFileBuffer[loop+3]) << 8) + int(inputFileBuffer[loop+4])
 
 			positionX = (int(inputFileBuffer[loop+5]) << 24) + (int(inputFileBuffer[loop+6]) << 16) + (int(inputFileBuffer[loop+7]) << 8) + int(inputFileBuffer[loop+8])
 
-			loop += 8
-		}
-
-		if character == 2 {
+		case 2:
 			colorForeground = color.RGBA{inputFileBuffer[loop+3], inputFileBuffer[loop+4], inputFileBuffer[loop+5], 255}
 
 			character = int(inputFileBuffer[loop+1])
 
-			loop += 5
-		}
-
-		if character == 4 {
+		case 4:
 			colorBackground = color.RGBA{inputFileBuffer[loop+3], inputFileBuffer[loop+4], inputFileBuffer[loop+5], 255}
 
 			character = int(inputFileBuffer[loop+1])
 
-			loop += 5
-		}
-
-		if character == 6 {
+		case 6:
 			colorForeground = color.RGBA{inputFileBuffer[loop+3], inputFileBuffer[loop+4], inputFileBuffer[loop+5], 255}
 			colorBackground = color.RGBA{inputFileBuffer[loop+7], inputFileBuffer[loop+8], inputFileBuffer[loop+9], 255}
 
 			character = int(inputFileBuffer[loop+1])
-
-			loop += 9
 		}
 
-		if character != 1 && character != 2 && character != 4 && character != 6 {
-			alDrawChar(imTundra, f.data, bits, f.sizeY, positionX, positionY, colorBackground, colorForeground, byte(character))
+		// the character of an opcode is drawn even if it is an opcode itself
+		if opcode != 1 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			canvas.Set(positionX, positionY, Cell{Char: byte(character), Fg: 7, FgRGB: colorForeground, BgRGB: colorBackground})
 
 			positionX++
 		}
 
-		loop++
+		loop += 1 + tundraOperandSize(opcode)
 	}
 
-	return imTundra
+	return canvas, nil
 }
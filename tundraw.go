@@ -0,0 +1,110 @@
+//  tundraw.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"encoding/binary"
+	"image/color"
+	"io"
+)
+
+// tundraVersion is the version byte Tundra files start with
+const tundraVersion = 24
+
+// EncodeTundra writes the canvas as a TundraDraw file, where every color
+// is a 24-bit one. Blanks on a black background are skipped with the
+// position opcode, which also starts each row, so that the file reads back
+// right with the columns set to the width of the canvas or more. Other
+// attributes than the colors are lost, blink among them.
+func (c *Canvas) EncodeTundra(w io.Writer) error {
+	if c.Width > tundraMaxPosition+1 || c.Height > tundraMaxPosition+1 {
+		return formatError(FormatTundra, 0, ErrBadOption)
+	}
+
+	buf := append([]byte{tundraVersion}, tundraHeader...)
+
+	// position opcode
+	moveTo := func(x, y int) {
+		var operand [8]byte
+		binary.BigEndian.PutUint32(operand[0:], uint32(y))
+		binary.BigEndian.PutUint32(operand[4:], uint32(x))
+		buf = append(append(buf, 1), operand[:]...)
+	}
+
+	// cursor and colors of the reader, unset to begin with
+	var fg, bg color.RGBA
+	x, y := 0, 0
+
+	for row := 0; row < c.Height; row++ {
+		end := c.Width
+		for end > 0 && c.tundraBlank(c.At(end-1, row)) {
+			end--
+		}
+
+		for col := 0; col < end; col++ {
+			cell := c.At(col, row)
+
+			// a long run of blanks is shorter as a move
+			n := 0
+			for col+n < end && c.tundraBlank(c.At(col+n, row)) {
+				n++
+			}
+			if n > 9 {
+				col += n - 1
+				continue
+			}
+
+			if x != col || y != row {
+				moveTo(col, row)
+				x, y = col, row
+			}
+
+			cellFg, cellBg := c.tundraColors(cell)
+
+			switch {
+			case cellFg != fg && cellBg != bg:
+				buf = append(buf, 6, cell.Char, 0, cellFg.R, cellFg.G, cellFg.B, 0, cellBg.R, cellBg.G, cellBg.B)
+			case cellBg != bg:
+				buf = append(buf, 4, cell.Char, 0, cellBg.R, cellBg.G, cellBg.B)
+			case cellFg != fg || cell.Char == 1 || cell.Char == 2 || cell.Char == 4 || cell.Char == 6:
+				// characters that are opcodes need one to be drawn
+				buf = append(buf, 2, cell.Char, 0, cellFg.R, cellFg.G, cellFg.B)
+			default:
+				buf = append(buf, cell.Char)
+			}
+
+			fg, bg = cellFg, cellBg
+			x++
+		}
+	}
+
+	// the reader takes the height from the last row it gets to
+	if c.Height > 0 {
+		moveTo(0, c.Height-1)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// tundraColors returns the 24-bit colors a cell is drawn with, transparent
+// ones become black
+func (c *Canvas) tundraColors(cell Cell) (fg, bg color.RGBA) {
+	fg, bg = c.CellColors(cell)
+	fg.A, bg.A = 255, 255
+	return fg, bg
+}
+
+// tundraBlank tells whether a cell looks like the ones the reader leaves
+// alone
+func (c *Canvas) tundraBlank(cell Cell) bool {
+	_, bg := c.tundraColors(cell)
+	return cell.Char == ' ' && bg == color.RGBA{0, 0, 0, 255} && cell.Attr&(AttrUnderline|AttrStrike) == 0
+}
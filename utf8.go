@@ -0,0 +1,141 @@
+//  utf8.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// ColorMode is the set of colors EncodeUTF8 may use
+type ColorMode int
+
+// Color modes of modern terminals
+const (
+	ColorsTrue ColorMode = iota // 24-bit colors
+	Colors256                   // the xterm 256 color palette
+	Colors16                    // the 16 ANSI colors
+)
+
+// utf8Attrs are the attributes EncodeUTF8 sets with SGR, the others are
+// part of the colors
+const utf8Attrs = AttrItalic | AttrUnderline | AttrBlink | AttrStrike
+
+// EncodeUTF8 writes the canvas for a modern terminal: characters mapped from
+// the code page of the font to UTF-8 and the colors of the canvas palette as
+// SGR sequences. Without 24-bit colors each color is the nearest one the
+// terminal has. Every row ends with a reset and a newline.
+func (c *Canvas) EncodeUTF8(w io.Writer, mode ColorMode) error {
+	enc := newUTF8Encoder(mode)
+
+	bw := bufio.NewWriter(w)
+
+	for y := 0; y < c.Height; y++ {
+		var prev cellStyle
+
+		for x := 0; x < c.Width; x++ {
+			cell := c.Cells[y*c.Width+x]
+			fg, bg := c.CellColors(cell)
+			style := cellStyle{fg, bg, cell.Attr & utf8Attrs}
+
+			if x == 0 || style != prev {
+				bw.WriteString(enc.sgr(style))
+				prev = style
+			}
+
+			bw.WriteRune(c.Font.toUnicode(cell.Char))
+		}
+
+		bw.WriteString("\x1b[0m\n")
+	}
+
+	return bw.Flush()
+}
+
+// utf8Encoder picks the SGR sequences of EncodeUTF8
+type utf8Encoder struct {
+	mode    ColorMode
+	xterm   Palette
+	nearest map[color.RGBA]int
+}
+
+func newUTF8Encoder(mode ColorMode) *utf8Encoder {
+	return &utf8Encoder{
+		mode:    mode,
+		xterm:   XtermPalette(),
+		nearest: make(map[color.RGBA]int),
+	}
+}
+
+// sgr returns the sequence that sets style, starting from a reset so that
+// nothing of the previous style is left
+func (e *utf8Encoder) sgr(style cellStyle) string {
+	seq := "\x1b[0"
+
+	if style.attr&AttrItalic != 0 {
+		seq += ";3"
+	}
+	if style.attr&AttrUnderline != 0 {
+		seq += ";4"
+	}
+	if style.attr&AttrBlink != 0 {
+		seq += ";5"
+	}
+	if style.attr&AttrStrike != 0 {
+		seq += ";9"
+	}
+
+	// transparent colors are left to the terminal
+	if style.fg.A > 0 {
+		seq += ";" + e.color(style.fg, 30)
+	}
+	if style.bg.A > 0 {
+		seq += ";" + e.color(style.bg, 40)
+	}
+
+	return seq + "m"
+}
+
+// color returns the SGR parameters of a foreground color for base 30, or a
+// background color for base 40
+func (e *utf8Encoder) color(rgba color.RGBA, base int) string {
+	switch e.mode {
+	case Colors16:
+		i := e.nearestColor(rgba)
+		if i < 8 {
+			return strconv.Itoa(base + i)
+		}
+		// aixterm bright colors
+		return strconv.Itoa(base + 60 + i - 8)
+	case Colors256:
+		return strconv.Itoa(base+8) + ";5;" + strconv.Itoa(e.nearestColor(rgba))
+	}
+
+	return strconv.Itoa(base+8) + ";2;" + strconv.Itoa(int(rgba.R)) + ";" +
+		strconv.Itoa(int(rgba.G)) + ";" + strconv.Itoa(int(rgba.B))
+}
+
+// nearestColor returns the xterm palette entry closest to rgba. The first 16
+// are left out of the 256 color mode, terminals often change them.
+func (e *utf8Encoder) nearestColor(rgba color.RGBA) int {
+	if i, ok := e.nearest[rgba]; ok {
+		return i
+	}
+
+	i := e.xterm.nearest(rgba, 16, 256)
+	if e.mode == Colors16 {
+		i = e.xterm.nearest(rgba, 0, 16)
+	}
+
+	e.nearest[rgba] = i
+	return i
+}
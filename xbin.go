@@ -15,21 +15,36 @@
 
 package goansi
 
-import (
-	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"os"
-)
-
-// Xbin processes inputFileBuffer and outputs image data
-func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
-	var f font
-
-	if string(inputFileBuffer[0:4]) == "XBIN\x1a" {
-		fmt.Print("\nNot an XBin.\n\n")
-		os.Exit(4)
+import "context"
+
+// xbinID is the signature at the start of every XBin file
+const xbinID = "XBIN\x1a"
+
+// xbinHeaderSize is the size of the fixed XBin header
+const xbinHeaderSize = 11
+
+// xbinRunBytes returns the number of bytes consumed by one character of a
+// compressed run, first is set for the run's first character
+func xbinRunBytes(ctype byte, first bool) int {
+	switch {
+	case ctype == 0 || first:
+		return 2
+	case ctype == 0xC0:
+		return 0
+	}
+	return 1
+}
+
+// Xbin processes inputFileBuffer and outputs a canvas
+func xbin(ctx context.Context, inputFileBuffer []byte, inputFileSize int64) (*Canvas, error) {
+	var f Font
+
+	if inputFileSize < xbinHeaderSize {
+		return nil, formatError(FormatXBin, int(inputFileSize), ErrTruncated)
+	}
+
+	if string(inputFileBuffer[0:len(xbinID)]) != xbinID {
+		return nil, formatError(FormatXBin, 0, ErrNotXBin)
 	}
 
 	var xbinWidth, xbinHeight, xbinFontSize, xbinFlags int
@@ -39,51 +54,25 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 	xbinFontSize = int(inputFileBuffer[9])
 	xbinFlags = int(inputFileBuffer[10])
 
-	var imXBIN draw.Image
-
-	imXBIN = image.NewRGBA(image.Rect(0, 0, 8*int(xbinWidth), int(xbinFontSize)*int(xbinHeight)))
-
-	if imXBIN == nil {
-		fmt.Printf("\nError, can't allocate buffer image memory.\n\n")
-		os.Exit(6)
+	// font height is stored in a single byte but can't exceed 32 lines
+	if xbinFontSize < 1 || xbinFontSize > 32 {
+		return nil, formatError(FormatXBin, 9, ErrBadHeader)
 	}
 
-	black := color.RGBA{0, 0, 0, 255}
-	draw.Draw(imXBIN, imXBIN.Bounds(), &image.Uniform{black}, image.ZP, draw.Src)
-
-	var colors [16]color.RGBA
-	offset := 11
+	var colors Palette
+	offset := xbinHeaderSize
 
 	// palette
 	if (xbinFlags & 1) == 1 {
-		var index int
-
-		for loop := 0; loop < 16; loop++ {
-			index = (loop * 3) + offset
-
-			colors[loop] = color.RGBA{(inputFileBuffer[index]<<2 | inputFileBuffer[index]>>4),
-				(inputFileBuffer[index+1]<<2 | inputFileBuffer[index+1]>>4),
-				(inputFileBuffer[index+2]<<2 | inputFileBuffer[index+2]>>4), 255}
+		if offset+48 > int(inputFileSize) {
+			return nil, formatError(FormatXBin, offset, ErrTruncated)
 		}
 
+		colors = readVGAPalette(inputFileBuffer[offset:], 16)
+
 		offset += 48
 	} else {
-		colors[0] = color.RGBA{0, 0, 0, 255}
-		colors[1] = color.RGBA{0, 0, 170, 255}
-		colors[2] = color.RGBA{0, 170, 0, 255}
-		colors[3] = color.RGBA{0, 170, 170, 255}
-		colors[4] = color.RGBA{170, 0, 0, 255}
-		colors[5] = color.RGBA{170, 0, 170, 255}
-		colors[6] = color.RGBA{170, 85, 0, 255}
-		colors[7] = color.RGBA{170, 170, 170, 255}
-		colors[8] = color.RGBA{85, 85, 85, 255}
-		colors[9] = color.RGBA{85, 85, 255, 255}
-		colors[10] = color.RGBA{85, 255, 85, 255}
-		colors[11] = color.RGBA{85, 255, 255, 255}
-		colors[12] = color.RGBA{255, 85, 85, 255}
-		colors[13] = color.RGBA{255, 85, 255, 255}
-		colors[14] = color.RGBA{255, 255, 85, 255}
-		colors[15] = color.RGBA{255, 255, 255, 255}
+		colors = vgaPalette.clone()
 	}
 
 	// font
@@ -96,10 +85,14 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 			numchars = 256
 		}
 
-		f.data = inputFileBuffer[offset : offset+(int(xbinFontSize)*numchars)]
-		f.sizeY = int(xbinFontSize)
-		f.sizeX = 8
-		f.isAmigaFont = false
+		if offset+xbinFontSize*numchars > int(inputFileSize) {
+			return nil, formatError(FormatXBin, offset, ErrTruncated)
+		}
+
+		f.Data = append([]byte(nil), inputFileBuffer[offset:offset+(int(xbinFontSize)*numchars)]...)
+		f.Height = int(xbinFontSize)
+		f.Width = 8
+		f.Amiga = false
 
 		offset += (int(xbinFontSize) * numchars)
 	} else {
@@ -107,8 +100,23 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 		alSelectFont(&f, "80x25")
 	}
 
+	// a damaged header can't ask for more cells than the budget, or than the
+	// data holds: two bytes per cell, or a run byte per 64 when compressed
+	cells := (int(inputFileSize) - offset) / 2
+	if (xbinFlags & 4) == 4 {
+		cells = (int(inputFileSize) - offset) * xbinMaxRun
+	}
+	if !canvasFits(xbinWidth, xbinHeight) || (xbinWidth > 0 && xbinHeight > cells/xbinWidth) {
+		return nil, formatError(FormatXBin, 5, ErrBadHeader)
+	}
+
+	canvas := NewCanvas(xbinWidth, xbinHeight, &f, colors)
+
 	var positionX, positionY int = 0, 0
-	var character, attribute, colorForeground, colorBackground int
+	var character, attribute int
+
+	// without the non-blink flag the high attribute bit makes text blink
+	blink := (xbinFlags & 8) == 0
 
 	// read compressed xbin
 	if (xbinFlags & 4) == 4 {
@@ -121,6 +129,10 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 
 			offset++
 			for i := counter; i > 0; i-- {
+				if offset+xbinRunBytes(ctype, i == counter) > int(inputFileSize) {
+					return nil, formatError(FormatXBin, offset, ErrTruncated)
+				}
+
 				// none
 				if ctype == 0 {
 					character = int(inputFileBuffer[offset])
@@ -154,10 +166,11 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 					}
 				}
 
-				colorBackground = (attribute & 240) >> 4
-				colorForeground = attribute & 15
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
 
-				alDrawChar(imXBIN, f.data, 8, 16, positionX, positionY, colors[colorBackground], colors[colorForeground], byte(character))
+				canvas.Set(positionX, positionY, xbinCell(character, attribute, blink))
 
 				positionX++
 
@@ -169,7 +182,7 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 		}
 	} else {
 		// read uncompressed xbin
-		for offset < int(inputFileSize) && positionY != int(xbinHeight) {
+		for offset+1 < int(inputFileSize) && positionY != int(xbinHeight) {
 			if positionX == int(xbinWidth) {
 				positionX = 0
 				positionY++
@@ -178,15 +191,26 @@ func xbin(inputFileBuffer []byte, inputFileSize int64) image.Image {
 			character = int(inputFileBuffer[offset])
 			attribute = int(inputFileBuffer[offset+1])
 
-			colorBackground = (attribute & 240) >> 4
-			colorForeground = attribute & 15
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 
-			alDrawChar(imXBIN, f.data, 8, int(xbinFontSize), positionX, positionY, colors[colorBackground], colors[colorForeground], byte(character))
+			canvas.Set(positionX, positionY, xbinCell(character, attribute, blink))
 
 			positionX++
 			offset += 2
 		}
 	}
 
-	return imXBIN
+	return canvas, nil
+}
+
+// xbinCell returns the cell for a character and its attribute byte
+func xbinCell(character, attribute int, blink bool) Cell {
+	cell := Cell{Char: byte(character), Fg: attribute & 15, Bg: (attribute & 240) >> 4}
+	if blink && attribute&0x80 != 0 {
+		cell.Bg &= 7
+		cell.Attr = AttrBlink
+	}
+	return cell
 }
@@ -0,0 +1,135 @@
+//  xbinw.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// xbinMaxRun is the most characters a compressed XBin run holds
+const xbinMaxRun = 64
+
+// EncodeXBin writes the canvas as a compressed XBin file, with its font and
+// the first 16 colors of its palette embedded, so that it shows the same
+// everywhere. Colors outside of them are written as the nearest one. The
+// non-blink flag is only left off for a canvas with blinking text, which
+// loses its bright backgrounds.
+func (c *Canvas) EncodeXBin(w io.Writer) error {
+	height := c.Font.Height
+	if c.Width < 1 || c.Width > 0xFFFF || c.Height > 0xFFFF || height < 1 || height > 32 {
+		return formatError(FormatXBin, 0, ErrBadOption)
+	}
+
+	blink := c.blinks()
+
+	// palette, font and compression
+	flags := byte(1 | 2 | 4)
+	if !blink {
+		flags |= 8
+	}
+
+	header := make([]byte, xbinHeaderSize)
+	copy(header, xbinID)
+	binary.LittleEndian.PutUint16(header[5:], uint16(c.Width))
+	binary.LittleEndian.PutUint16(header[7:], uint16(c.Height))
+	header[9] = byte(height)
+	header[10] = flags
+
+	colors := vgaPaletteBytes(c.Palette, 16)
+
+	buf := append([]byte(nil), header...)
+	buf = append(buf, colors...)
+
+	buf = append(buf, c.Font.glyphs()...)
+
+	// the colors as they are read back
+	palette := readVGAPalette(colors, 16)
+
+	chars := make([]byte, c.Width)
+	attrs := make([]byte, c.Width)
+
+	for y := 0; y < c.Height; y++ {
+		for x := range chars {
+			cell := c.Cells[y*c.Width+x]
+			chars[x] = cell.Char
+			attrs[x] = c.textAttribute(cell, palette, blink)
+		}
+
+		buf = xbinCompress(buf, chars, attrs)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// xbinCompress appends a row of characters and attributes to buf as XBin
+// runs. Runs of the same character and attribute, of the same character or
+// of the same attribute are stored once, anything else as it is.
+func xbinCompress(buf []byte, chars, attrs []byte) []byte {
+	n := len(chars)
+
+	// run returns how many cells from i are equal to cell i by eq
+	run := func(i int, eq func(j int) bool) int {
+		k := 1
+		for i+k < n && k < xbinMaxRun && eq(i+k) {
+			k++
+		}
+		return k
+	}
+
+	// runs returns the runs of both, the character and the attribute from i
+	runs := func(i int) (both, char, attr int) {
+		both = run(i, func(j int) bool { return chars[j] == chars[i] && attrs[j] == attrs[i] })
+		char = run(i, func(j int) bool { return chars[j] == chars[i] })
+		attr = run(i, func(j int) bool { return attrs[j] == attrs[i] })
+		return both, char, attr
+	}
+
+	// a run is worth it when it saves more than the byte of a new run
+	worth := func(i int) bool {
+		both, char, attr := runs(i)
+		return both >= 2 || char >= 3 || attr >= 3
+	}
+
+	for i := 0; i < n; {
+		both, char, attr := runs(i)
+
+		switch {
+		case both >= 2:
+			buf = append(buf, 0xC0|byte(both-1), chars[i], attrs[i])
+			i += both
+
+		case char >= 3:
+			buf = append(buf, 0x40|byte(char-1), chars[i])
+			buf = append(buf, attrs[i:i+char]...)
+			i += char
+
+		case attr >= 3:
+			buf = append(buf, 0x80|byte(attr-1), attrs[i])
+			buf = append(buf, chars[i:i+attr]...)
+			i += attr
+
+		default:
+			k := 1
+			for i+k < n && k < xbinMaxRun && !worth(i+k) {
+				k++
+			}
+
+			buf = append(buf, byte(k-1))
+			for j := i; j < i+k; j++ {
+				buf = append(buf, chars[j], attrs[j])
+			}
+			i += k
+		}
+	}
+
+	return buf
+}
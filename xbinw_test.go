@@ -0,0 +1,107 @@
+//  xbinw_test.go
+//  go-ansi
+//
+// Copyright (C) 2017 ActiveState Software Inc.
+//
+//  go-ansi is licensed under the BSD 3-Clause License.
+//  See the file LICENSE for details.
+//
+
+package goansi
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"testing"
+)
+
+// runsCanvas returns a canvas wider than the longest XBin run, with rows of
+// repeated cells, characters, attributes and no repeats at all
+func runsCanvas(blink bool) *Canvas {
+	font := *SelectFont("80x25")
+	font.Data = append([]byte(nil), font.Data...)
+	font.Data['A'*16] = 0xAA
+
+	palette := vgaPalette.clone()
+	palette[3] = color.RGBA{4, 8, 12, 255}
+
+	c := NewCanvas(100, 4, &font, palette)
+	for x := 0; x < c.Width; x++ {
+		c.Set(x, 0, Cell{Char: 'A', Fg: 3, Bg: 12})
+		c.Set(x, 1, Cell{Char: 0xDB, Fg: x % 16, Bg: x / 16 % 16})
+		c.Set(x, 2, Cell{Char: byte(x), Fg: 15, Bg: 1})
+		c.Set(x, 3, Cell{Char: byte(x * 7), Fg: x * 3 % 16, Bg: x % 16})
+	}
+
+	if blink {
+		c.Set(5, 2, Cell{Char: 'B', Fg: 14, Bg: 4, Attr: AttrBlink})
+	}
+
+	return c
+}
+
+func TestEncodeXBin(t *testing.T) {
+	for _, blink := range []bool{false, true} {
+		c := runsCanvas(blink)
+
+		var buf bytes.Buffer
+		if err := c.EncodeXBin(&buf); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+
+		// compressed with the palette and the font, iCE colors unless there
+		// is blinking text
+		wantFlags := byte(1 | 2 | 4)
+		if !blink {
+			wantFlags |= 8
+		}
+		if data[10] != wantFlags {
+			t.Errorf("blink %v: flags %#x, want %#x", blink, data[10], wantFlags)
+		}
+
+		got := decodeData(t, data, RenderOptions{})
+
+		if got.Width != c.Width || got.Height != c.Height {
+			t.Fatalf("blink %v: got %dx%d, want %dx%d", blink, got.Width, got.Height, c.Width, c.Height)
+		}
+		for i, want := range c.Cells {
+			// blinking takes the bit of the bright backgrounds
+			if blink {
+				want.Bg &= 7
+			}
+			if got.Cells[i] != want {
+				t.Errorf("blink %v: cell %d,%d is %+v, want %+v", blink, i%c.Width, i/c.Width, got.Cells[i], want)
+			}
+		}
+		for i := 0; i < 16; i++ {
+			if got.Palette[i] != c.Palette[i] {
+				t.Errorf("blink %v: color %d is %v, want %v", blink, i, got.Palette[i], c.Palette[i])
+			}
+		}
+		if !bytes.Equal(got.Font.Data, c.Font.glyphs()) {
+			t.Errorf("blink %v: the font doesn't read back", blink)
+		}
+	}
+}
+
+func TestEncodeXBinSize(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := NewCanvas(0, 1, SelectFont("80x25"), vgaPalette.clone())
+	if err := c.EncodeXBin(&buf); !errors.Is(err, ErrBadOption) {
+		t.Errorf("empty canvas: got %v, want ErrBadOption", err)
+	}
+
+	// fonts without a glyph for every character are padded
+	font := *SelectFont("80x25")
+	font.Data = font.Data[:16]
+	c = NewCanvas(1, 1, &font, vgaPalette.clone())
+	if err := c.EncodeXBin(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeData(t, buf.Bytes(), RenderOptions{}); len(got.Font.Data) != 256*16 {
+		t.Errorf("got %d bytes of font, want %d", len(got.Font.Data), 256*16)
+	}
+}